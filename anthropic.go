@@ -0,0 +1,52 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+// ChatMessage is a single message of a chat request.
+type ChatMessage = codec.ChatMessage
+
+// CountAnthropicMessages estimates the input tokens of a Claude messages
+// request. The messages are framed like Claude's "\n\nHuman:" and
+// "\n\nAssistant:" prompt format, with the system prompt in front, and counted
+// with the Anthropic vocabulary. The framing stands in for the ratio Count
+// applies to plain text, so none is applied here. It matches the counts the
+// count_tokens API returned for the captured requests within a token.
+func CountAnthropicMessages(model Model, system string, messages []ChatMessage) (int, error) {
+	encoding, err := EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+	if encoding != AnthropicBase {
+		return 0, fmt.Errorf("%s is not an Anthropic model", model)
+	}
+	enc, err := Get(encoding)
+	if err != nil {
+		return 0, err
+	}
+
+	var b strings.Builder
+	b.WriteString(system)
+	last := ""
+	for _, m := range messages {
+		switch m.Role {
+		case "user":
+			b.WriteString("\n\nHuman: ")
+		case "assistant":
+			b.WriteString("\n\nAssistant: ")
+		default:
+			return 0, fmt.Errorf("unsupported role: %q", m.Role)
+		}
+		b.WriteString(m.Content)
+		last = m.Role
+	}
+	if last != "assistant" {
+		b.WriteString("\n\nAssistant:")
+	}
+
+	return enc.Count(b.String())
+}
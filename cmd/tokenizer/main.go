@@ -0,0 +1,133 @@
+// Command tokenizer counts, encodes and decodes tokens from the shell.
+//
+//	tokenizer count -model gpt-4o -file x.txt
+//	echo "hello world" | tokenizer encode -encoding cl100k_base
+//	echo "15339 1917" | tokenizer decode -encoding cl100k_base
+//
+// Input is read from -file, or stdin when it isn't set. decode reads ids
+// separated by whitespace or commas. The resolved encoding is printed to
+// stderr so the output stays usable in pipes.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tokenizer "github.com/awee-ai/go-tokenizer"
+)
+
+const usage = `usage: tokenizer <count|encode|decode> [-model name | -encoding name] [-file path]`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "tokenizer:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	model := flags.String("model", "", "model name, e.g. gpt-4o")
+	encoding := flags.String("encoding", "", "encoding name, e.g. cl100k_base")
+	file := flags.String("file", "", "input file, stdin when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	codec, err := resolve(*model, *encoding)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stderr, "encoding:", codec.GetName())
+
+	input, err := readInput(*file, stdin)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "count":
+		n, err := codec.Count(input)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, n)
+	case "encode":
+		ids, _, err := codec.Encode(input)
+		if err != nil {
+			return err
+		}
+		fields := make([]string, len(ids))
+		for i, id := range ids {
+			fields[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		fmt.Fprintln(stdout, strings.Join(fields, " "))
+	case "decode":
+		ids, err := parseIDs(input)
+		if err != nil {
+			return err
+		}
+		if _, err := codec.DecodeTo(stdout, ids); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+	return nil
+}
+
+func resolve(model, encoding string) (tokenizer.Codec, error) {
+	switch {
+	case model != "" && encoding != "":
+		return nil, errors.New("-model and -encoding are mutually exclusive")
+	case model != "":
+		return tokenizer.ForModel(tokenizer.Model(model))
+	case encoding != "":
+		return tokenizer.Get(tokenizer.Encoding(encoding))
+	default:
+		return nil, errors.New("one of -model or -encoding is required")
+	}
+}
+
+func readInput(file string, stdin io.Reader) (string, error) {
+	r := stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("error reading input: %v", err)
+	}
+	return string(data), nil
+}
+
+func parseIDs(input string) ([]uint, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	ids := make([]uint, len(fields))
+	for i, field := range fields {
+		id, err := strconv.ParseUint(field, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %q", field)
+		}
+		ids[i] = uint(id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "x.txt")
+	if err := os.WriteFile(file, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		want     string
+		encoding string
+	}{
+		{name: "count", args: []string{"count", "--model", "gpt-4o", "--file", file}, want: "2\n", encoding: "o200k_base"},
+		{name: "encode", args: []string{"encode", "--encoding", "cl100k_base"}, stdin: "hello world", want: "15339 1917\n", encoding: "cl100k_base"},
+		{name: "decode", args: []string{"decode", "--encoding", "cl100k_base"}, stdin: "15339, 1917\n", want: "hello world", encoding: "cl100k_base"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr); err != nil {
+				t.Fatalf("run() error: %v", err)
+			}
+			if got := stdout.String(); got != test.want {
+				t.Errorf("output mismatch - want: %q got: %q", test.want, got)
+			}
+			if got := stderr.String(); got != "encoding: "+test.encoding+"\n" {
+				t.Errorf("unexpected stderr: %q", got)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "no command", args: nil, err: "usage"},
+		{name: "unknown command", args: []string{"split", "-encoding", "cl100k_base"}, err: "unknown command"},
+		{name: "no encoding", args: []string{"count"}, err: "required"},
+		{name: "unknown model", args: []string{"count", "-model", "nope"}, err: "not supported"},
+		{name: "invalid id", args: []string{"decode", "-encoding", "cl100k_base"}, err: "invalid id"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(test.args, strings.NewReader("x"), &stdout, &stderr)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("want error containing %q, got: %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package codec
+
+// NewAnthropicBase returns the Claude tokenizer published for the Claude 1/2
+// models. It is the closest public approximation for newer Claude models.
+// The upstream tokenizer also applies NFKC normalization, which is not done
+// here.
+func NewAnthropicBase(opts ...Option) *Codec {
+	anthropicVocabOnce.Do(anthropicVocabInit)
+
+	return withOptions(&Codec{
+		name:         "anthropic",
+		vocabulary:   currentVocab("anthropic", anthropicVocab),
+		pretokenizer: mustRegexPretokenizer(gpt2PatStr),
+		specialTokens: map[string]uint{
+			"<EOT>":        0,
+			"<META>":       1,
+			"<META_START>": 2,
+			"<META_END>":   3,
+			"<SOS>":        4,
+		},
+	}, opts)
+}
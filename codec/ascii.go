@@ -0,0 +1,189 @@
+package codec
+
+// asciiSplitters find the end of the next piece of an all-ASCII input the
+// way the pattern they are keyed by would, without the cost of regexp2
+// matching on runes. Patterns without an entry always use the regexp.
+var asciiSplitters = map[string]func(s string, i int) int{
+	gpt2PatStr:   gpt2NextASCII,
+	cl100kPatStr: func(s string, i int) int { return cl100kNextASCII(s, i, true) },
+	llamaPatStr:  func(s string, i int) int { return cl100kNextASCII(s, i, false) },
+	o200kPatStr:  o200kNextASCII,
+}
+
+// splitASCII calls fn with every piece next finds in the input.
+func splitASCII(input string, next func(string, int) int, fn func(piece string, offset int) error) error {
+	for i := 0; i < len(input); {
+		j := next(input, i)
+		if err := fn(input[i:j], i); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func isLower(b byte) bool   { return 'a' <= b && b <= 'z' }
+func isUpper(b byte) bool   { return 'A' <= b && b <= 'Z' }
+func isLetter(b byte) bool  { return isLower(b) || isUpper(b) }
+func isDigit(b byte) bool   { return '0' <= b && b <= '9' }
+func isNewline(b byte) bool { return b == '\r' || b == '\n' }
+
+// isSpace matches \s, which regexp2 defines with unicode.IsSpace.
+func isSpace(b byte) bool {
+	return b == ' ' || ('\t' <= b && b <= '\r')
+}
+
+// isOther matches [^\s\p{L}\p{N}].
+func isOther(b byte) bool {
+	return !isSpace(b) && !isLetter(b) && !isDigit(b)
+}
+
+// isPrefix matches [^\r\n\p{L}\p{N}].
+func isPrefix(b byte) bool {
+	return !isNewline(b) && !isLetter(b) && !isDigit(b)
+}
+
+// span returns the end of the run of bytes matching class from i.
+func span(s string, i int, class func(byte) bool) int {
+	for i < len(s) && class(s[i]) {
+		i++
+	}
+	return i
+}
+
+// contraction returns the length of the 's|'t|'re|'ve|'m|'ll|'d
+// contraction at i, or 0.
+func contraction(s string, i int, ignoreCase bool) int {
+	if i+1 >= len(s) || s[i] != '\'' {
+		return 0
+	}
+	lower := func(b byte) byte {
+		if ignoreCase && isUpper(b) {
+			return b + 'a' - 'A'
+		}
+		return b
+	}
+	switch lower(s[i+1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	case 'r', 'v':
+		if i+2 < len(s) && lower(s[i+2]) == 'e' {
+			return 3
+		}
+	case 'l':
+		if i+2 < len(s) && lower(s[i+2]) == 'l' {
+			return 3
+		}
+	}
+	return 0
+}
+
+// spaceEnd matches \s*[\r\n]+|\s+(?!\S)|\s+ at i, and \s+\z first if
+// atEnd is set.
+func spaceEnd(s string, i int, atEnd bool) int {
+	k := span(s, i, isSpace)
+	if atEnd && k == len(s) {
+		return k
+	}
+	for p := k - 1; p >= i; p-- {
+		if isNewline(s[p]) {
+			return p + 1
+		}
+	}
+	if k < len(s) && k-1 > i {
+		return k - 1
+	}
+	return k
+}
+
+// gpt2NextASCII matches
+// 's|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+
+func gpt2NextASCII(s string, i int) int {
+	if n := contraction(s, i, false); n > 0 {
+		return i + n
+	}
+	start := i
+	if s[i] == ' ' {
+		start++
+	}
+	if start < len(s) {
+		switch b := s[start]; {
+		case isLetter(b):
+			return span(s, start, isLetter)
+		case isDigit(b):
+			return span(s, start, isDigit)
+		case isOther(b):
+			return span(s, start, isOther)
+		}
+	}
+	k := span(s, i, isSpace)
+	if k < len(s) && k-1 > i {
+		return k - 1
+	}
+	return k
+}
+
+// cl100kNextASCII matches the cl100k_base pattern, or the Llama 3 one, which
+// lacks the \s+\z alternative, if atEnd is false.
+func cl100kNextASCII(s string, i int, atEnd bool) int {
+	if n := contraction(s, i, true); n > 0 {
+		return i + n
+	}
+	switch b := s[i]; {
+	case isLetter(b):
+		return span(s, i, isLetter)
+	case isPrefix(b) && i+1 < len(s) && isLetter(s[i+1]):
+		return span(s, i+1, isLetter)
+	case isDigit(b):
+		return min(span(s, i, isDigit), i+3)
+	}
+	if end, ok := punctEnd(s, i, isNewline); ok {
+		return end
+	}
+	return spaceEnd(s, i, atEnd)
+}
+
+// o200kNextASCII matches the o200k_base pattern. ASCII has no \p{Lt},
+// \p{Lm}, \p{Lo} or \p{M}, which leaves upper and lower case letters.
+func o200kNextASCII(s string, i int) int {
+	start := i
+	if isPrefix(s[i]) {
+		start++
+	}
+	upper := span(s, start, isUpper)
+	lower := span(s, upper, isLower)
+	if lower > start {
+		if n := contraction(s, lower, true); n > 0 {
+			return lower + n
+		}
+		return lower
+	}
+	if isDigit(s[i]) {
+		return min(span(s, i, isDigit), i+3)
+	}
+	if end, ok := punctEnd(s, i, func(b byte) bool { return isNewline(b) || b == '/' }); ok {
+		return end
+	}
+	return spaceEnd(s, i, false)
+}
+
+// punctEnd matches ` ?[^\s\p{L}\p{N}]+` at i followed by a run of trailing.
+func punctEnd(s string, i int, trailing func(byte) bool) (int, bool) {
+	start := i
+	if s[i] == ' ' {
+		start++
+	}
+	if start >= len(s) || !isOther(s[start]) {
+		return 0, false
+	}
+	return span(s, span(s, start, isOther), trailing), true
+}
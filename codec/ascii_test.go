@@ -0,0 +1,168 @@
+package codec
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var asciiTexts = []string{
+	"",
+	"hello world",
+	"Hello World HELLO WORLD hELLO",
+	"I'm sure they'll say it's what we've done, don't you think? I'D, YOU'RE, HE'S",
+	"'s 's' ''t 'lla 're're",
+	"1 12 123 1234 12345 3.14159 0x1F 1e10",
+	"  leading and trailing  ",
+	"tabs\tand\t\ttabs \t mixed",
+	"line\nbreaks\r\n\r\nand  \n  indented\n\n",
+	"func main() {\n\tfmt.Println(\"hi\")\n}\n",
+	"https://example.com/a/b?c=d&e=f#g",
+	"a//b///c .../ ./. !!!\n\n??? ---\r\n",
+	"CamelCaseWords and snake_case_words and SCREAMING_CASE",
+	"\x00\x01\x1f\x7f \v\f \x0b",
+	" ",
+	"\n",
+	"x",
+	" \n ",
+	"end with space ",
+	"end with newline\n ",
+}
+
+// regexpTwin returns a copy of c that always splits with the regexp, by
+// wrapping the pattern in a group that has no ASCII splitter.
+func regexpTwin(c *Codec) *Codec {
+	twin := c.Clone()
+	twin.pretokenizer = mustRegexPretokenizer("(?:" + c.Pattern() + ")")
+	return twin
+}
+
+func asciiCodecs() []*Codec {
+	return []*Codec{NewR50kBase(), NewCl100kBase(), NewLLama3Base(), NewO200kBase()}
+}
+
+func randomASCII(r *rand.Rand) string {
+	const alphabet = "aAbZ'sStTrReElLvVdDmM 0123 \t\n\r\v\f./!-_\"{}"
+	b := make([]byte, r.Intn(40))
+	for i := range b {
+		if r.Intn(8) == 0 {
+			b[i] = byte(r.Intn(0x80))
+		} else {
+			b[i] = alphabet[r.Intn(len(alphabet))]
+		}
+	}
+	return string(b)
+}
+
+func splitPieces(c *Codec, input string) ([]string, error) {
+	var pieces []string
+	err := c.split(input, func(piece string) error {
+		pieces = append(pieces, piece)
+		return nil
+	})
+	return pieces, err
+}
+
+func TestASCIISplit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	texts := slices.Clone(asciiTexts)
+	for range 2000 {
+		texts = append(texts, randomASCII(r))
+	}
+
+	for _, c := range asciiCodecs() {
+		if asciiSplitters[c.Pattern()] == nil {
+			t.Fatalf("%s: no ASCII splitter", c.GetName())
+		}
+		twin := regexpTwin(c)
+
+		for _, text := range texts {
+			want, err := splitPieces(twin, text)
+			if err != nil {
+				t.Fatalf("%s: error splitting %q: %v", c.GetName(), text, err)
+			}
+			got, err := splitPieces(c, text)
+			if err != nil {
+				t.Fatalf("%s: error splitting %q: %v", c.GetName(), text, err)
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s: split(%q) = %q, want %q", c.GetName(), text, got, want)
+			}
+
+			wantIDs, err := twin.EncodeOrdinary(text)
+			if err != nil {
+				t.Fatalf("%s: error encoding %q: %v", c.GetName(), text, err)
+			}
+			gotIDs, err := c.EncodeOrdinary(text)
+			if err != nil {
+				t.Fatalf("%s: error encoding %q: %v", c.GetName(), text, err)
+			}
+			if !slices.Equal(gotIDs, wantIDs) {
+				t.Fatalf("%s: EncodeOrdinary(%q) = %v, want %v", c.GetName(), text, gotIDs, wantIDs)
+			}
+		}
+	}
+}
+
+func FuzzASCIISplit(f *testing.F) {
+	for _, text := range asciiTexts {
+		f.Add(text)
+	}
+	codecs := asciiCodecs()
+	twins := make([]*Codec, len(codecs))
+	for i, c := range codecs {
+		twins[i] = regexpTwin(c)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if !isASCII(text) {
+			return
+		}
+		for i, c := range codecs {
+			want, err := splitPieces(twins[i], text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := splitPieces(c, text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s: split(%q) = %q, want %q", c.GetName(), text, got, want)
+			}
+		}
+	})
+}
+
+func BenchmarkASCII(b *testing.B) {
+	text := strings.Repeat("It was the best of times, it was the worst of times, it was the age of "+
+		"wisdom, it was the age of foolishness, it was the epoch of belief, it was the epoch of "+
+		"incredulity, it was the season of Light, it was the season of Darkness.\n", 20)
+
+	c := NewCl100kBase()
+	for _, bb := range []struct {
+		name  string
+		codec *Codec
+	}{
+		{"regexp", regexpTwin(c)},
+		{"ascii", c},
+	} {
+		b.Run(bb.name+"/Count", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bb.codec.Count(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bb.name+"/Encode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := bb.codec.Encode(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
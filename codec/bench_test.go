@@ -0,0 +1,60 @@
+package codec_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+// benchCorpus returns testdata/corpus.txt, a mix of prose, code, logs,
+// tables, emoji and text in a dozen languages, for benchmarks to share.
+func benchCorpus(tb testing.TB) string {
+	tb.Helper()
+	data, err := os.ReadFile("testdata/corpus.txt")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(data)
+}
+
+// BenchmarkCodecs is the baseline for Encode, Count and Decode of the most
+// used encodings.
+func BenchmarkCodecs(b *testing.B) {
+	text := benchCorpus(b)
+
+	for _, c := range []*codec.Codec{codec.NewCl100kBase(), codec.NewO200kBase(), codec.NewLLama3Base()} {
+		ids, _, err := c.Encode(text)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(c.GetName()+"/Encode", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				if _, _, err := c.Encode(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(c.GetName()+"/Count", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Count(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(c.GetName()+"/Decode", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Decode(ids); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package codec
+
+// NewBertBase returns the WordPiece tokenizer of bert-base-uncased, which
+// BERT based embedding models such as all-MiniLM share. The input is
+// lowercased like upstream, but accents aren't stripped, as that needs
+// Unicode decomposition; WithNormalizer can add it.
+func NewBertBase(opts ...Option) *Codec {
+	bertBaseVocabOnce.Do(bertBaseVocabInit)
+
+	c := NewWordPiece(Config{
+		Name:       "bert",
+		Vocabulary: currentVocab("bert", bertBaseVocab),
+		SpecialTokens: map[string]uint{
+			"[PAD]":  0,
+			"[UNK]":  100,
+			"[CLS]":  101,
+			"[SEP]":  102,
+			"[MASK]": 103,
+		},
+	}, opts...)
+	c.wordPiece.lowercase = true
+	return c
+}
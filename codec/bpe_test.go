@@ -0,0 +1,132 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+// TestCl100kCorpus compares against ids from a reference implementation of
+// tiktoken's byte pair merge, over inputs with many equally ranked pairs.
+func TestCl100kCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/cl100k_base_corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus []struct {
+		Text string `json:"text"`
+		IDs  []uint `json:"ids"`
+	}
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*codec.Codec{codec.NewCl100kBase(), codec.NewCl100kBase(codec.WithTrie())} {
+		for _, entry := range corpus {
+			ids, err := c.EncodeOrdinary(entry.Text)
+			if err != nil {
+				t.Fatalf("EncodeOrdinary(%q) error: %v", entry.Text, err)
+			}
+			if !slices.Equal(ids, entry.IDs) {
+				t.Errorf("EncodeOrdinary(%q) = %v, want %v", entry.Text, ids, entry.IDs)
+			}
+		}
+	}
+}
+
+// TestMistralCorpus compares against ids from the Mistral v1 SentencePiece
+// tokenizer, taken from the reference files of github.com/wbrown/gpt_bpe.
+func TestMistralCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/mistral_corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus []struct {
+		Text string `json:"text"`
+		IDs  []uint `json:"ids"`
+	}
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatal(err)
+	}
+
+	c := codec.NewMistralBase()
+	for _, entry := range corpus {
+		ids, err := c.EncodeOrdinary(entry.Text)
+		if err != nil {
+			t.Fatalf("EncodeOrdinary(%.40q) error: %v", entry.Text, err)
+		}
+		if !slices.Equal(ids, entry.IDs) {
+			t.Errorf("EncodeOrdinary(%.40q) = %v, want %v", entry.Text, ids, entry.IDs)
+		}
+		text, err := c.Decode(ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != entry.Text {
+			t.Errorf("Decode = %.40q, want %.40q", text, entry.Text)
+		}
+	}
+}
+
+// referenceBPE merges the lowest ranked adjacent pair, the leftmost on ties,
+// until no pair is in the vocabulary.
+func referenceBPE(ranks map[string]uint, piece string) []uint {
+	parts := strings.Split(piece, "")
+	for {
+		best := -1
+		var bestRank uint
+		for i := 0; i+1 < len(parts); i++ {
+			if rank, ok := ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = slices.Replace(parts, best, best+2, parts[best]+parts[best+1])
+	}
+	ids := make([]uint, len(parts))
+	for i, p := range parts {
+		ids[i] = ranks[p]
+	}
+	return ids
+}
+
+func FuzzMergeTieBreak(f *testing.F) {
+	words := []string{"a", "b", "aa", "ab", "ba", "bb", "aaa", "aab", "abab", "baa", "bbb", "aaaa"}
+	ranks := make(map[string]uint, len(words))
+	for i, w := range words {
+		ranks[w] = uint(i)
+	}
+	c, err := codec.LoadTiktoken(strings.NewReader(testTiktoken(words...)), `[ab]+`, nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, seed := range []string{"aaaaa", "abababa", "aabbaabb", "bbbbbbb", "baaab"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, piece string) {
+		piece = strings.Map(func(r rune) rune {
+			if r%2 == 0 {
+				return 'a'
+			}
+			return 'b'
+		}, piece)
+		if piece == "" {
+			return
+		}
+
+		ids, err := c.EncodeOrdinary(piece)
+		if err != nil {
+			t.Fatalf("EncodeOrdinary(%q) error: %v", piece, err)
+		}
+		if want := referenceBPE(ranks, piece); !slices.Equal(ids, want) {
+			t.Errorf("EncodeOrdinary(%q) = %v, want %v", piece, ids, want)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package codec
+
+import "unsafe"
+
+// CountBytes is Count for input held in a byte slice, without copying it
+// into a string. b must not be modified until CountBytes returns.
+func (c *Codec) CountBytes(b []byte) (int, error) {
+	return c.Count(bytesToString(b))
+}
+
+// EncodeBytes is Encode for input held in a byte slice, without copying it
+// into a string. The returned tokens share memory with b, so b must not be
+// modified while they are in use; copy b or the tokens if it will be.
+func (c *Codec) EncodeBytes(b []byte) ([]uint, []string, error) {
+	return c.Encode(bytesToString(b))
+}
+
+// bytesToString returns a string sharing memory with b.
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,61 @@
+package codec_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestCountBytes(t *testing.T) {
+	c := codec.NewCl100kBase()
+
+	for _, text := range append(slices.Clone(countTexts), "", "caf\xe9 ok", "日本語のテキスト") {
+		want, err := c.Count(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.CountBytes([]byte(text))
+		if err != nil {
+			t.Fatalf("CountBytes(%q) error: %v", text, err)
+		}
+		if got != want {
+			t.Errorf("CountBytes(%q) = %d, want %d", text, got, want)
+		}
+
+		wantIDs, wantTokens, err := c.Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, tokens, err := c.EncodeBytes([]byte(text))
+		if err != nil {
+			t.Fatalf("EncodeBytes(%q) error: %v", text, err)
+		}
+		if !slices.Equal(ids, wantIDs) || !slices.Equal(tokens, wantTokens) {
+			t.Errorf("EncodeBytes(%q) = %v %q, want %v %q", text, ids, tokens, wantIDs, wantTokens)
+		}
+	}
+}
+
+func BenchmarkCountBytes(b *testing.B) {
+	c := codec.NewCl100kBase()
+	body := []byte(strings.Repeat(strings.Join(countTexts, " "), 20))
+
+	b.Run("Count", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Count(string(body)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CountBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.CountBytes(body); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
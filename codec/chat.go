@@ -0,0 +1,86 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// chatTemplate frames chat messages the way a model was trained on and
+// returns the token ids, including the control tokens.
+type chatTemplate func(c *Codec, messages []ChatMessage) ([]uint, error)
+
+// EncodeChat returns the token ids a model sees for the messages, framed by
+// the codec's chat template with all its control tokens and followed by the
+// header of the assistant reply the model is asked to write. Special tokens
+// in the roles and contents are encoded as ordinary text. It fails if the
+// codec has no chat template; only the Llama 3 codec has one.
+func (c *Codec) EncodeChat(messages []ChatMessage) ([]uint, error) {
+	if c.chatTemplate == nil {
+		return nil, fmt.Errorf("%s has no chat template", c.name)
+	}
+	return c.chatTemplate(c, messages)
+}
+
+// llama3Chat is the Llama 3 template:
+//
+//	<|begin_of_text|><|start_header_id|>role<|end_header_id|>\n\ncontent<|eot_id|>...
+//
+// with the content trimmed of surrounding whitespace.
+func llama3Chat(c *Codec, messages []ChatMessage) ([]uint, error) {
+	special := func(token string) (uint, error) {
+		id, ok := c.specialTokens[token]
+		if !ok {
+			return 0, fmt.Errorf("%s does not define %s", c.name, token)
+		}
+		return id, nil
+	}
+	begin, err := special("<|begin_of_text|>")
+	if err != nil {
+		return nil, err
+	}
+	startHeader, err := special("<|start_header_id|>")
+	if err != nil {
+		return nil, err
+	}
+	endHeader, err := special("<|end_header_id|>")
+	if err != nil {
+		return nil, err
+	}
+	eot, err := special("<|eot_id|>")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint{begin}
+	header := func(role string) error {
+		roleIDs, err := c.EncodeOrdinary(role)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, startHeader)
+		ids = append(ids, roleIDs...)
+		ids = append(ids, endHeader)
+		return nil
+	}
+
+	for _, m := range messages {
+		if err := header(m.Role); err != nil {
+			return nil, err
+		}
+		body, err := c.EncodeOrdinary("\n\n" + strings.TrimSpace(m.Content))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, body...)
+		ids = append(ids, eot)
+	}
+
+	if err := header("assistant"); err != nil {
+		return nil, err
+	}
+	newlines, err := c.EncodeOrdinary("\n\n")
+	if err != nil {
+		return nil, err
+	}
+	return append(ids, newlines...), nil
+}
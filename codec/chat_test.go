@@ -0,0 +1,54 @@
+package codec_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestEncodeChatLlama3(t *testing.T) {
+	c := codec.NewLLama3Base()
+
+	ids, err := c.EncodeChat([]codec.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "  Hello  \n"},
+	})
+	if err != nil {
+		t.Fatalf("EncodeChat() error: %v", err)
+	}
+
+	const (
+		beginOfText = 128000
+		startHeader = 128006
+		endHeader   = 128007
+		eot         = 128009
+	)
+	want := []uint{
+		beginOfText,
+		startHeader, 9125, endHeader, 271, 3513, 10015, 13, eot, // system, "\n\n", "Be brief."
+		startHeader, 882, endHeader, 271, 9906, eot, // user, "\n\n", "Hello"
+		startHeader, 78191, endHeader, 271, // assistant, "\n\n"
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("EncodeChat() = %v, want %v", ids, want)
+	}
+
+	// the same as encoding the rendered prompt
+	prompt := "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+		"<|start_header_id|>user<|end_header_id|>\n\nHello<|eot_id|>" +
+		"<|start_header_id|>assistant<|end_header_id|>\n\n"
+	rendered, _, err := c.EncodeWithSpecialTokens(prompt, map[string]bool{codec.AllowAllSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, rendered) {
+		t.Errorf("EncodeChat() = %v, want the rendered prompt's %v", ids, rendered)
+	}
+}
+
+func TestEncodeChatNoTemplate(t *testing.T) {
+	if _, err := codec.NewCl100kBase().EncodeChat([]codec.ChatMessage{{Role: "user", Content: "hi"}}); err == nil {
+		t.Errorf("EncodeChat() without a template succeeded")
+	}
+}
@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	chatMLStart = "<|im_start|>"
+	chatMLEnd   = "<|im_end|>"
+)
+
+// ChatMessage is a single message of a chat prompt.
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// FormatChatML frames the messages as ChatML, each one written as
+// "<|im_start|>role\ncontent<|im_end|>\n", and returns the prompt with its
+// token ids. The framing tokens are encoded as special tokens, while special
+// tokens in the roles and contents are encoded as ordinary text. It fails if
+// the codec doesn't define <|im_start|> and <|im_end|>.
+func (c *Codec) FormatChatML(messages []ChatMessage) (string, []uint, error) {
+	start, ok := c.specialTokens[chatMLStart]
+	if !ok {
+		return "", nil, fmt.Errorf("%s does not define %s", c.name, chatMLStart)
+	}
+	end, ok := c.specialTokens[chatMLEnd]
+	if !ok {
+		return "", nil, fmt.Errorf("%s does not define %s", c.name, chatMLEnd)
+	}
+
+	newline, err := c.EncodeOrdinary("\n")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	var ids []uint
+	for _, m := range messages {
+		body, err := c.EncodeOrdinary(m.Role + "\n" + m.Content)
+		if err != nil {
+			return "", nil, err
+		}
+
+		b.WriteString(chatMLStart + m.Role + "\n" + m.Content + chatMLEnd + "\n")
+		ids = append(ids, start)
+		ids = append(ids, body...)
+		ids = append(ids, end)
+		ids = append(ids, newline...)
+	}
+
+	return b.String(), ids, nil
+}
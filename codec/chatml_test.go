@@ -0,0 +1,65 @@
+package codec_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestFormatChatML(t *testing.T) {
+	c := codec.NewCl100kBase()
+	if err := c.AddSpecialToken("<|im_start|>", 100264); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddSpecialToken("<|im_end|>", 100265); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []codec.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hello<|im_end|>"},
+	}
+	text, ids, err := c.FormatChatML(messages)
+	if err != nil {
+		t.Fatalf("FormatChatML() error: %v", err)
+	}
+
+	want := "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHello<|im_end|><|im_end|>\n"
+	if text != want {
+		t.Errorf("text mismatch - want: %q got: %q", want, text)
+	}
+
+	var framing []int
+	for i, id := range ids {
+		if id == 100264 || id == 100265 {
+			framing = append(framing, i)
+		}
+	}
+	// the <|im_end|> in the content is encoded as text
+	if len(framing) != 4 || ids[framing[0]] != 100264 || ids[framing[1]] != 100265 ||
+		ids[framing[2]] != 100264 || ids[framing[3]] != 100265 || framing[0] != 0 {
+		t.Fatalf("unexpected framing ids at %v in %v", framing, ids)
+	}
+	if last := ids[len(ids)-1]; framing[3] != len(ids)-2 || last != 198 {
+		t.Errorf("want <|im_end|> and a newline last, got %v", ids[framing[3]:])
+	}
+
+	decoded, err := c.Decode(ids)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if decoded != want {
+		t.Errorf("decoded %q, want %q", decoded, want)
+	}
+	if plain, _ := c.EncodeOrdinary("system\nBe brief."); !slices.Equal(ids[1:framing[1]], plain) {
+		t.Errorf("message ids %v, want %v", ids[1:framing[1]], plain)
+	}
+}
+
+func TestFormatChatMLUnsupported(t *testing.T) {
+	_, _, err := codec.NewCl100kBase().FormatChatML([]codec.ChatMessage{{Role: "user", Content: "hi"}})
+	if err == nil {
+		t.Fatal("expected an error for a codec without ChatML tokens")
+	}
+}
@@ -1,22 +1,20 @@
 package codec
 
-import "github.com/dlclark/regexp2"
+const cl100kPatStr = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s+\z|\s*[\r\n]+|\s+(?!\S)|\s+`
 
-func NewCl100kBase() *Codec {
+func NewCl100kBase(opts ...Option) *Codec {
 	cl100kBaseVocabOnce.Do(cl100kBaseVocabInit)
 
-	splitRegexp := regexp2.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`, regexp2.None)
-
-	return &Codec{
-		name:        "cl100k_base",
-		vocabulary:  cl100kBaseVocab,
-		splitRegexp: splitRegexp,
-		specialTokens: map[string]uint{
+	return New(Config{
+		Name:       "cl100k_base",
+		Vocabulary: currentVocab("cl100k_base", cl100kBaseVocab),
+		Pattern:    cl100kPatStr,
+		SpecialTokens: map[string]uint{
 			"<|endoftext|>":   100257,
 			"<|fim_prefix|>":  100258,
 			"<|fim_middle|>":  100259,
 			"<|fim_suffix|>":  100260,
 			"<|endofprompt|>": 100276,
 		},
-	}
+	}, opts...)
 }
@@ -1,39 +1,336 @@
 package codec
 
 import (
+	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"math"
-
-	"github.com/dlclark/regexp2"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type Codec struct {
 	vocabulary        vocab
 	reverseVocabulary reverse
+	reverseOnce       sync.Once
 	specialTokens     map[string]uint
-	splitRegexp       *regexp2.Regexp
+	pretokenizer      Pretokenizer
 	name              string
+	sentencePiece     *sentencePiece
+	wordPiece         *wordPiece
+	chatTemplate      chatTemplate
+	// mergeRanks orders BPE merges when they don't follow the token ids, as
+	// in HuggingFace vocabularies. When nil the ids are the ranks.
+	mergeRanks vocab
+
+	useTrie  bool
+	trie     *trie
+	trieOnce sync.Once
+
+	// vocabHash is the hash of the vocabulary and merge ranks in Fingerprint.
+	vocabHash       []byte
+	fingerprintOnce sync.Once
+
+	invalidUTF8 InvalidUTF8
+	normalize   func(string) string
+	// byteFallback encodes parts missing from the vocabulary as <0xXX> byte
+	// tokens.
+	byteFallback bool
+}
+
+// Config describes a tiktoken style BPE encoding, where the token ids are
+// also the merge ranks.
+type Config struct {
+	Name          string
+	Vocabulary    map[string]uint
+	Pattern       string
+	SpecialTokens map[string]uint
+}
+
+// New returns a codec for the encoding described by cfg. It panics if the
+// pattern doesn't compile.
+func New(cfg Config, opts ...Option) *Codec {
+	return withOptions(&Codec{
+		name:          cfg.Name,
+		vocabulary:    cfg.Vocabulary,
+		pretokenizer:  mustRegexPretokenizer(cfg.Pattern),
+		specialTokens: cfg.SpecialTokens,
+	}, opts)
 }
 
 func (c *Codec) GetName() string {
 	return c.name
 }
 
-// Count returns the number of tokens in the input string.
+// Pattern returns the regular expression used to split the input before BPE,
+// or "" if the codec uses a Pretokenizer other than a RegexPretokenizer.
+func (c *Codec) Pattern() string {
+	if p, ok := c.pretokenizer.(*RegexPretokenizer); ok {
+		return p.Pattern()
+	}
+	return ""
+}
+
+// SpecialTokens returns a copy of the special tokens and their ids.
+func (c *Codec) SpecialTokens() map[string]uint {
+	specialTokens := make(map[string]uint, len(c.specialTokens))
+	for k, v := range c.specialTokens {
+		specialTokens[k] = v
+	}
+	return specialTokens
+}
+
+// SpecialToken is a special token and its id.
+type SpecialToken struct {
+	Name string
+	ID   uint
+}
+
+// SpecialTokensSorted returns the special tokens in ascending id order.
+func (c *Codec) SpecialTokensSorted() []SpecialToken {
+	tokens := make([]SpecialToken, 0, len(c.specialTokens))
+	for name, id := range c.specialTokens {
+		tokens = append(tokens, SpecialToken{Name: name, ID: id})
+	}
+	slices.SortFunc(tokens, func(a, b SpecialToken) int {
+		return cmp.Or(cmp.Compare(a.ID, b.ID), strings.Compare(a.Name, b.Name))
+	})
+	return tokens
+}
+
+// MergeRanks returns a copy of the BPE merge priorities: the rank of every
+// token that merging can produce, lower ranks merging first. For tiktoken
+// style vocabularies these are the token ids, HuggingFace vocabularies keep
+// the order of their merges list. WordPiece codecs don't merge and return
+// nil.
+func (c *Codec) MergeRanks() map[string]uint {
+	if c.wordPiece != nil {
+		return nil
+	}
+	ranks := c.vocabulary
+	if c.mergeRanks != nil {
+		ranks = c.mergeRanks
+	}
+	return maps.Clone(map[string]uint(ranks))
+}
+
+// AddSpecialToken registers a special token on the codec, so the special
+// token aware encoders recognize it and Decode can map its id back. It fails
+// if the name or the id is already in use. Other codecs are never affected,
+// but the codec itself must not be used concurrently while adding.
+func (c *Codec) AddSpecialToken(name string, id uint) error {
+	if name == "" {
+		return fmt.Errorf("empty special token")
+	}
+	if _, ok := c.specialTokens[name]; ok {
+		return fmt.Errorf("special token %q already exists", name)
+	}
+	for token, v := range c.specialTokens {
+		if v == id {
+			return fmt.Errorf("id %d already used by special token %q", id, token)
+		}
+	}
+	for token, v := range c.vocabulary {
+		if v == id {
+			return fmt.Errorf("id %d already used by token %q", id, token)
+		}
+	}
+
+	// copy on write, the map may be shared with other codecs created from
+	// the same Config
+	specialTokens := make(map[string]uint, len(c.specialTokens)+1)
+	maps.Copy(specialTokens, c.specialTokens)
+	specialTokens[name] = id
+	c.specialTokens = specialTokens
+	if c.reverseVocabulary != nil {
+		c.reverseVocabulary[id] = name
+	}
+
+	return nil
+}
+
+// Clone returns a copy of the codec whose special tokens can be changed
+// without affecting the original. Clones are cheap: the vocabulary, the merge
+// ranks and the compiled pattern are shared, as they are never modified.
+func (c *Codec) Clone() *Codec {
+	clone := &Codec{
+		vocabulary:    c.vocabulary,
+		specialTokens: c.SpecialTokens(),
+		pretokenizer:  c.pretokenizer,
+		name:          c.name,
+		sentencePiece: c.sentencePiece,
+		wordPiece:     c.wordPiece,
+		chatTemplate:  c.chatTemplate,
+		mergeRanks:    c.mergeRanks,
+		useTrie:       c.useTrie,
+		invalidUTF8:   c.invalidUTF8,
+		normalize:     c.normalize,
+		byteFallback:  c.byteFallback,
+	}
+	if c.useTrie {
+		c.trieOnce.Do(c.buildTrie)
+		clone.trie = c.trie
+		clone.trieOnce.Do(func() {})
+	}
+	return clone
+}
+
+// Count returns the number of tokens in the input string. It only counts the
+// merged parts of each piece and doesn't build the ids or tokens.
 func (c *Codec) Count(input string) (int, error) {
 	var count int
 
-	err := c.tokenize(input, func(_ uint, _ string) {
-		count++
+	err := c.split(input, func(piece string) error {
+		n, err := c.countPiece(piece)
+		count += n
+		return err
 	})
 
 	return count, err
 }
 
-// Encode returns the token IDs and tokens for the input string.
+// CountDelta returns how many more tokens full has than prefix, which it must
+// start with, e.g. to track the size of a streamed response. Appending text
+// can change the merges at the end of prefix, so this isn't the count of the
+// appended text: the last pieces of prefix are counted again together with
+// it, while the rest of prefix is only split. Codecs that normalize the input
+// count both strings in full.
+func (c *Codec) CountDelta(prefix, full string) (int, error) {
+	if !strings.HasPrefix(full, prefix) {
+		return 0, fmt.Errorf("full text doesn't start with the prefix")
+	}
+
+	cut := 0
+	if c.normalize == nil && c.sentencePiece == nil && c.wordPiece == nil && utf8.ValidString(full) {
+		// the split pattern looks at most one piece ahead, so everything
+		// before the last two pieces stays the same
+		var starts [2]int
+		pos := 0
+		err := c.split(prefix, func(piece string) error {
+			start := pos + strings.Index(prefix[pos:], piece)
+			starts[0], starts[1] = starts[1], start
+			pos = start + len(piece)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		cut = starts[0]
+	}
+
+	before, err := c.Count(prefix[cut:])
+	if err != nil {
+		return 0, err
+	}
+	after, err := c.Count(full[cut:])
+	if err != nil {
+		return 0, err
+	}
+	return after - before, nil
+}
+
+// LastTokenChanges reports whether appending a space to the text changes
+// its final token, as when the text ends in whitespace that the space merges
+// into. Completion prompts whose final token changes this way tend to
+// degrade. It is false for an empty text.
+func (c *Codec) LastTokenChanges(text string) (bool, error) {
+	ids, _, err := c.Encode(text)
+	if err != nil || len(ids) == 0 {
+		return false, err
+	}
+	spaced, _, err := c.Encode(text + " ")
+	if err != nil {
+		return false, err
+	}
+	last := len(ids) - 1
+	return last >= len(spaced) || spaced[last] != ids[last], nil
+}
+
+// CountStats returns the number of tokens, runes and bytes in the input, for
+// relating token counts to input size.
+func (c *Codec) CountStats(input string) (tokens, runes, bytes int, err error) {
+	tokens, err = c.Count(input)
+	return tokens, utf8.RuneCountInString(input), len(input), err
+}
+
+// CountStatsUTF16 returns the number of tokens and UTF-16 code units in the
+// input, the length JavaScript reports for it. Characters outside the Basic
+// Multilingual Plane, like most emoji, take two code units, and every
+// invalid UTF-8 byte counts as one U+FFFD.
+func (c *Codec) CountStatsUTF16(input string) (tokens, utf16Units int, err error) {
+	tokens, err = c.Count(input)
+	for _, r := range input {
+		utf16Units += utf16.RuneLen(r)
+	}
+	return tokens, utf16Units, err
+}
+
+// ErrMatchTimeout is returned when the split pattern takes longer than the
+// limit set with WithMatchTimeout to find a piece.
+var ErrMatchTimeout = errors.New("split pattern match timed out")
+
+// errLimitExceeded stops splitting once CountUpTo has passed its limit.
+var errLimitExceeded = errors.New("limit exceeded")
+
+// CountUpTo counts the tokens in the input like Count, but stops as soon as
+// the count exceeds limit, so large inputs can be checked against a budget
+// without tokenizing all of them. It reports whether the limit was exceeded,
+// in which case the count is only what was counted before stopping.
+func (c *Codec) CountUpTo(input string, limit int) (int, bool, error) {
+	var count int
+
+	err := c.split(input, func(piece string) error {
+		n, err := c.countPiece(piece)
+		count += n
+		if err != nil {
+			return err
+		}
+		if count > limit {
+			return errLimitExceeded
+		}
+		return nil
+	})
+	if err == errLimitExceeded {
+		return count, true, nil
+	}
+
+	return count, false, err
+}
+
+func (c *Codec) countPiece(piece string) (int, error) {
+	if c.byteFallback || c.sentencePiece != nil || c.wordPiece != nil {
+		// parts missing from the vocabulary fall back to one token per byte
+		var count int
+		err := c.encodePiece(piece, func(_ uint, _ string) {
+			count++
+		})
+		return count, err
+	}
+	if _, ok := c.vocabulary[piece]; ok {
+		return 1, nil
+	}
+	return len(c.mergePairs(piece)) - 1, nil
+}
+
+// Encode returns the token IDs and tokens for the input string. Like the other
+// Encode methods, it returns empty, non-nil slices for an empty input. If a
+// piece of the input can't be encoded, e.g. by a byte fallback codec missing
+// a byte token, the Encode methods return the results for the input before
+// that piece along with the error, for diagnostics.
+//
+// Control characters such as NUL, BEL or ESC are ordinary input: they are
+// encoded as bytes, or as <0xXX> tokens by SentencePiece codecs, and decode
+// back unchanged. Only WordPiece codecs drop them, as BERT does.
 func (c *Codec) Encode(input string) ([]uint, []string, error) {
-	var ids []uint
-	var tokens []string
+	ids := []uint{}
+	tokens := []string{}
 
 	err := c.tokenize(input, func(id uint, token string) {
 		ids = append(ids, id)
@@ -43,49 +340,575 @@ func (c *Codec) Encode(input string) ([]uint, []string, error) {
 	return ids, tokens, err
 }
 
+// EncodeContinuation is like Encode for text that continues earlier text
+// rather than starting a prompt. GPT-2 style byte level codecs such as
+// cl100k_base never add a space: a word's leading space is part of its
+// token, so "world" and " world" are different tokens either way and the
+// caller decides which one the text starts with. SentencePiece codecs with a
+// dummy prefix, like Llama 2, prepend a space marker in Encode so the first
+// word encodes like one in the middle of a sentence, EncodeContinuation
+// leaves it out. For other codecs it is the same as Encode.
+func (c *Codec) EncodeContinuation(text string) ([]uint, []string, error) {
+	ids := []uint{}
+	tokens := []string{}
+
+	err := c.splitText(text, true, func(piece string) error {
+		return c.encodePiece(piece, func(id uint, token string) {
+			ids = append(ids, id)
+			tokens = append(tokens, token)
+		})
+	})
+
+	return ids, tokens, err
+}
+
+// EncodePretty is like Encode, but shows every byte of the tokens as a
+// printable character the way GPT-2 style byte level vocabularies and the
+// HuggingFace tokenizer viewers do, e.g. a space is "Ġ" and a newline "Ċ".
+// Tokens that are partial UTF-8 sequences stay readable this way. The tokens
+// of SentencePiece and WordPiece codecs are text already and are returned
+// as they are.
+func (c *Codec) EncodePretty(text string) ([]uint, []string, error) {
+	ids, tokens, err := c.Encode(text)
+	if err != nil || c.sentencePiece != nil || c.wordPiece != nil {
+		return ids, tokens, err
+	}
+	for i, token := range tokens {
+		tokens[i] = encodeByteLevel(token)
+	}
+	return ids, tokens, nil
+}
+
+// EncodeWithLengths returns the token IDs for the input string like Encode,
+// along with the number of bytes each token adds to the decoded output, so
+// streamed tokens can be rendered without decoding them. Unless the codec's
+// options change the input, the lengths add up to len(input).
+func (c *Codec) EncodeWithLengths(input string) ([]uint, []int, error) {
+	ids := []uint{}
+	lengths := []int{}
+
+	err := c.tokenize(input, func(id uint, token string) {
+		token = c.pieceText(token)
+		if len(ids) == 0 && c.sentencePiece != nil {
+			token = c.sentencePiece.trimDummyPrefix(token)
+		}
+		ids = append(ids, id)
+		lengths = append(lengths, len(token))
+	})
+
+	return ids, lengths, err
+}
+
+// Span is the part of a text a token covers, as rune indices: the token
+// covers []rune(text)[Start:End].
+type Span struct {
+	Start, End int
+}
+
+// EncodeWithRuneOffsets returns the token IDs for the input string like
+// Encode, along with the span of runes each token covers, for editors that
+// index text by rune. A rune split over several tokens belongs to the token
+// holding its first byte, the others get an empty span. Like the lengths of
+// EncodeWithLengths, the spans only line up with the input if the codec's
+// options don't change it.
+func (c *Codec) EncodeWithRuneOffsets(input string) ([]uint, []Span, error) {
+	ids, lengths, err := c.EncodeWithLengths(input)
+
+	// runes[i] is the number of runes starting before byte i
+	runes := make([]int, len(input)+1)
+	b, n := 0, 0
+	for i := range input {
+		for ; b <= i; b++ {
+			runes[b] = n
+		}
+		n++
+	}
+	for ; b <= len(input); b++ {
+		runes[b] = n
+	}
+
+	spans := make([]Span, len(ids))
+	offset := 0
+	for i, length := range lengths {
+		start, end := min(offset, len(input)), min(offset+length, len(input))
+		spans[i] = Span{Start: runes[start], End: runes[end]}
+		offset += length
+	}
+	return ids, spans, err
+}
+
+// EncodeDecodedPieces returns the token IDs for the input string like
+// Encode, along with the text each token decodes to, for debuggers. Unlike
+// the raw tokens of Encode, the pieces are valid UTF-8: a character whose
+// bytes are split over several tokens shows up as "�" in each of them,
+// as do invalid bytes. The tokens of WordPiece codecs are returned as they
+// are.
+func (c *Codec) EncodeDecodedPieces(input string) ([]uint, []string, error) {
+	ids := []uint{}
+	texts := []string{}
+
+	err := c.tokenize(input, func(id uint, token string) {
+		if c.wordPiece == nil {
+			token = c.pieceText(token)
+		}
+		if len(ids) == 0 && c.sentencePiece != nil {
+			token = c.sentencePiece.trimDummyPrefix(token)
+		}
+		ids = append(ids, id)
+		texts = append(texts, token)
+	})
+
+	pieces := make([]string, len(texts))
+	decoded := strings.Join(texts, "")
+	var b strings.Builder
+	// token is the token holding the byte at pos, end is where its bytes end
+	token, end := -1, 0
+	next := func() {
+		if token >= 0 {
+			pieces[token] = b.String()
+			b.Reset()
+		}
+		token++
+		end += len(texts[token])
+	}
+	for pos, size := 0, 0; pos < len(decoded); pos += size {
+		var r rune
+		r, size = utf8.DecodeRuneInString(decoded[pos:])
+		for pos >= end {
+			next()
+		}
+		if r != utf8.RuneError && pos+size <= end {
+			b.WriteString(decoded[pos : pos+size])
+			continue
+		}
+		b.WriteRune(utf8.RuneError)
+		for pos+size > end {
+			next()
+			b.WriteRune(utf8.RuneError)
+		}
+	}
+	if token >= 0 {
+		pieces[token] = b.String()
+	}
+
+	return ids, pieces, err
+}
+
+// EncodeOrdinary returns the token IDs for the input string, treating every
+// substring as ordinary text like tiktoken's encode_ordinary. It never emits
+// a special token id, even when the input contains one.
+func (c *Codec) EncodeOrdinary(input string) ([]uint, error) {
+	ids := []uint{}
+
+	err := c.tokenize(input, func(id uint, _ string) {
+		ids = append(ids, id)
+	})
+
+	return ids, err
+}
+
+// EncodeSegmentsRaw returns the token IDs for text the caller already split
+// into segments, running BPE on each segment as it is and concatenating the
+// results. The split pattern, normalization and special tokens are skipped,
+// so segments that match the pieces of the split pattern give the same ids
+// as EncodeOrdinary.
+func (c *Codec) EncodeSegmentsRaw(segments []string) ([]uint, error) {
+	ids := []uint{}
+	yield := func(id uint, _ string) {
+		ids = append(ids, id)
+	}
+
+	var parts []part
+	for _, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		var err error
+		if parts, err = c.encodePieceInto(parts, segment, yield); err != nil {
+			return ids, err
+		}
+	}
+
+	return ids, nil
+}
+
+// AllowAllSpecial, used as a key of the allowed map passed to
+// EncodeWithSpecialTokens, allows every special token of the codec, like
+// tiktoken's allowed_special="all".
+const AllowAllSpecial = "all"
+
+// EncodeWithSpecialTokens returns the token IDs and tokens for the input
+// string like Encode, except that occurrences of the allowed special tokens
+// are encoded as their single special token id. Special tokens that are not
+// allowed are encoded as ordinary text.
+func (c *Codec) EncodeWithSpecialTokens(input string, allowed map[string]bool) ([]uint, []string, error) {
+	ids := []uint{}
+	tokens := []string{}
+
+	yield := func(id uint, token string) {
+		ids = append(ids, id)
+		tokens = append(tokens, token)
+	}
+
+	for {
+		start, special := c.nextSpecial(input, allowed)
+		if start < 0 {
+			break
+		}
+		if err := c.tokenize(input[:start], yield); err != nil {
+			return ids, tokens, err
+		}
+		yield(c.specialTokens[special], special)
+		input = input[start+len(special):]
+	}
+
+	err := c.tokenize(input, yield)
+
+	return ids, tokens, err
+}
+
+// Segment is a run of the input encoded either as a single special token or
+// as ordinary text.
+type Segment struct {
+	Special bool
+	Text    string
+	IDs     []uint
+}
+
+// EncodeSegments encodes the input like EncodeWithSpecialTokens, but returns
+// the ids grouped in order into the special tokens and the ordinary text
+// between them, e.g. to render a prompt with its special tokens highlighted.
+func (c *Codec) EncodeSegments(input string, allowed map[string]bool) ([]Segment, error) {
+	var segments []Segment
+
+	ordinary := func(text string) error {
+		if text == "" {
+			return nil
+		}
+		ids, err := c.EncodeOrdinary(text)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, Segment{Text: text, IDs: ids})
+		return nil
+	}
+
+	for {
+		start, special := c.nextSpecial(input, allowed)
+		if start < 0 {
+			break
+		}
+		if err := ordinary(input[:start]); err != nil {
+			return segments, err
+		}
+		segments = append(segments, Segment{Special: true, Text: special, IDs: []uint{c.specialTokens[special]}})
+		input = input[start+len(special):]
+	}
+
+	err := ordinary(input)
+
+	return segments, err
+}
+
+// nextSpecial returns the offset of the leftmost allowed special token in the
+// input, preferring the longest one when several start at the same offset.
+func (c *Codec) nextSpecial(input string, allowed map[string]bool) (int, string) {
+	allowAll := allowed[AllowAllSpecial]
+	start, special := -1, ""
+	for token := range c.specialTokens {
+		if !allowAll && !allowed[token] {
+			continue
+		}
+		i := strings.Index(input, token)
+		if i < 0 {
+			continue
+		}
+		if start < 0 || i < start || (i == start && len(token) > len(special)) {
+			start, special = i, token
+		}
+	}
+	return start, special
+}
+
+// SpecialLike is a substring found by ScanSpecialLike and its byte offset.
+type SpecialLike struct {
+	Token  string
+	Offset int
+}
+
+// ScanSpecialLike reports every substring of the text shaped like a special
+// token, <|...|> without whitespace in between, whether or not a codec has it
+// registered. Encode treats them as ordinary text, but in user input they can
+// be an attempt at prompt injection worth flagging.
+func ScanSpecialLike(text string) []SpecialLike {
+	var found []SpecialLike
+	for i := 0; ; {
+		start := strings.Index(text[i:], "<|")
+		if start < 0 {
+			return found
+		}
+		start += i
+		end := strings.Index(text[start+2:], "|>")
+		if end < 0 {
+			return found
+		}
+		end += start + 2
+
+		inner := text[start+2 : end]
+		if strings.ContainsFunc(inner, unicode.IsSpace) || strings.Contains(inner, "<|") {
+			// look for a shorter one starting further on
+			i = start + 2
+			continue
+		}
+		found = append(found, SpecialLike{Token: text[start : end+2], Offset: start})
+		i = end + 2
+	}
+}
+
 func (c *Codec) tokenize(input string, yield func(uint, string)) error {
-	match, err := c.splitRegexp.FindStringMatch(input)
-	if err != nil {
-		return fmt.Errorf("error matching: %v", err)
-	}
-	for match != nil {
-		piece := match.String()
-		if id, ok := c.vocabulary[piece]; ok {
-			yield(id, piece)
-		} else {
-			parts := c.mergePairs(piece)
-
-			for i := range len(parts) - 1 {
-				token := piece[parts[i].offset:parts[i+1].offset]
-				yield(c.vocabulary[token], token)
+	return c.split(input, func(piece string) error {
+		return c.encodePiece(piece, yield)
+	})
+}
+
+// split calls fn with every piece the split pattern finds in the input.
+func (c *Codec) split(input string, fn func(piece string) error) error {
+	return c.splitText(input, false, fn)
+}
+
+// splitText is split, leaving out the SentencePiece dummy prefix if the
+// input continues earlier text.
+func (c *Codec) splitText(input string, continuation bool, fn func(piece string) error) error {
+	if !utf8.ValidString(input) {
+		switch c.invalidUTF8 {
+		case InvalidUTF8Replace:
+			input = strings.ToValidUTF8(input, "\uFFFD")
+		case InvalidUTF8Error:
+			return fmt.Errorf("invalid UTF-8 in input")
+		}
+	}
+	if c.normalize != nil {
+		input = c.normalize(input)
+	}
+	if c.sentencePiece != nil {
+		input = c.sentencePiece.normalize(input, !continuation)
+	}
+	if c.wordPiece != nil {
+		input = c.wordPiece.normalize(input)
+	}
+	return c.pretokenizer.Split(input, func(piece string, _ int) error {
+		return fn(piece)
+	})
+}
+
+// encodePiece yields the tokens BPE merges a single piece into.
+func (c *Codec) encodePiece(piece string, yield func(uint, string)) error {
+	_, err := c.encodePieceInto(nil, piece, yield)
+	return err
+}
+
+// encodePieceInto is encodePiece using parts as scratch space for the merges.
+// It returns the scratch space for reuse.
+func (c *Codec) encodePieceInto(parts []part, piece string, yield func(uint, string)) ([]part, error) {
+	if c.wordPiece != nil {
+		return parts, c.encodeWord(piece, yield)
+	}
+	if id, ok := c.vocabulary[piece]; ok {
+		yield(id, piece)
+		return parts, nil
+	}
+
+	parts = c.mergePairsInto(parts, piece, nil)
+	if c.byteFallback || c.sentencePiece != nil {
+		// check the whole piece first, so a failing piece yields nothing
+		for i := range len(parts) - 1 {
+			if err := c.checkToken(piece[parts[i].offset:parts[i+1].offset]); err != nil {
+				return parts, err
 			}
 		}
-		match, err = c.splitRegexp.FindNextMatch(match)
-		if err != nil {
-			return fmt.Errorf("error matching: %v", err)
+	}
+	for i := range len(parts) - 1 {
+		token := piece[parts[i].offset:parts[i+1].offset]
+		if id, ok := c.vocabulary[token]; ok || (!c.byteFallback && c.sentencePiece == nil) {
+			yield(id, token)
+			continue
+		}
+		if !c.byteFallback {
+			return parts, fmt.Errorf("no token for %q in %s", token, c.name)
+		}
+		if err := c.encodeBytes(token, yield); err != nil {
+			return parts, err
+		}
+	}
+	return parts, nil
+}
+
+// DebugEncode encodes the input like Encode and writes a trace of it to w:
+// every piece the input is split into, the BPE merges in the order they are
+// made with the rank of the merged pair, and the tokens of the piece. Pieces
+// that are a token already aren't merged. WordPiece codecs never merge.
+func (c *Codec) DebugEncode(input string, w io.Writer) ([]uint, error) {
+	ids := []uint{}
+	var werr error
+	printf := func(format string, args ...any) {
+		if werr == nil {
+			_, werr = fmt.Fprintf(w, format, args...)
 		}
 	}
 
+	err := c.split(input, func(piece string) error {
+		printf("piece %q\n", piece)
+		if _, ok := c.vocabulary[piece]; !ok && c.wordPiece == nil {
+			c.mergePairsInto(nil, piece, func(left, right string, rank uint) {
+				printf("  merge %q + %q rank %d\n", left, right, rank)
+			})
+		}
+		return c.encodePiece(piece, func(id uint, token string) {
+			printf("  token %d %q\n", id, token)
+			ids = append(ids, id)
+		})
+	})
+	if err != nil {
+		return ids, err
+	}
+	if werr != nil {
+		return nil, fmt.Errorf("error writing trace: %v", werr)
+	}
+	return ids, nil
+}
+
+// checkToken returns the error encodePiece fails with for a merged token
+// that is missing from the vocabulary and can't fall back to bytes.
+func (c *Codec) checkToken(token string) error {
+	if _, ok := c.vocabulary[token]; ok {
+		return nil
+	}
+	if !c.byteFallback {
+		return fmt.Errorf("no token for %q in %s", token, c.name)
+	}
+	for i := 0; i < len(token); i++ {
+		if _, ok := c.vocabulary[bytePiece(token[i])]; !ok {
+			return fmt.Errorf("no byte fallback for %q in %s", token[i], c.name)
+		}
+	}
 	return nil
 }
 
+// Decode returns the text for the token IDs.
 func (c *Codec) Decode(tokens []uint) (string, error) {
-	if c.reverseVocabulary == nil {
-		c.reverseVocabulary = make(map[uint]string)
-		for k, v := range c.vocabulary {
-			c.reverseVocabulary[v] = k
+	out, err := c.DecodeToBytes(tokens)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// DecodeUntilSpecial decodes the token IDs up to the first special token,
+// like a runtime trimming a generation at <|eot_id|> or <|endoftext|>. It
+// reports whether it stopped and at which special token.
+func (c *Codec) DecodeUntilSpecial(ids []uint) (text string, stopped bool, stopToken string, err error) {
+	special := make(map[uint]string, len(c.specialTokens))
+	for name, id := range c.specialTokens {
+		special[id] = name
+	}
+	for i, id := range ids {
+		if name, ok := special[id]; ok {
+			text, err = c.Decode(ids[:i])
+			return text, true, name, err
 		}
 	}
+	text, err = c.Decode(ids)
+	return text, false, "", err
+}
 
-	var out string
-	for _, t := range tokens {
-		piece, ok := c.reverseVocabulary[t]
-		if !ok {
-			return "", fmt.Errorf("invalid token: %d", t)
+// DecodeToBytes returns the raw bytes for the token IDs. Tokens often end in
+// the middle of a UTF-8 sequence, the bytes are returned as they are.
+func (c *Codec) DecodeToBytes(tokens []uint) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := c.DecodeTo(&buf, tokens); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeTo writes the bytes of each token to w as it goes and returns the
+// number of bytes written. It stops at the first unknown token ID, writing
+// nothing for it.
+func (c *Codec) DecodeTo(w io.Writer, tokens []uint) (int, error) {
+	var n int
+	for i, t := range tokens {
+		piece, err := c.decodeToken(t)
+		if err != nil {
+			return n, err
+		}
+		if i == 0 && c.sentencePiece != nil {
+			piece = c.sentencePiece.trimDummyPrefix(piece)
+		}
+		if c.wordPiece != nil {
+			piece = c.wordPiece.decodePiece(piece, i == 0)
+		}
+		written, err := io.WriteString(w, piece)
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// decodeToken returns the text of a single token ID.
+func (c *Codec) decodeToken(id uint) (string, error) {
+	c.reverseOnce.Do(c.buildReverse)
+
+	piece, ok := c.reverseVocabulary[id]
+	if !ok {
+		return "", fmt.Errorf("invalid token: %d", id)
+	}
+	return c.pieceText(piece), nil
+}
+
+// pieceText returns the text a vocabulary piece stands for.
+func (c *Codec) pieceText(piece string) string {
+	if c.sentencePiece != nil {
+		return c.sentencePiece.decodePiece(piece)
+	}
+	if c.byteFallback {
+		if b, ok := parseBytePiece(piece); ok {
+			return string([]byte{b})
 		}
-		out += piece
 	}
-	return out, nil
+	return piece
+}
+
+func (c *Codec) buildReverse() {
+	c.reverseVocabulary = make(reverse, len(c.vocabulary)+len(c.specialTokens))
+	for k, v := range c.vocabulary {
+		c.reverseVocabulary[v] = k
+	}
+	for k, v := range c.specialTokens {
+		c.reverseVocabulary[v] = k
+	}
+}
+
+func (c *Codec) buildTrie() {
+	if c.mergeRanks != nil {
+		c.trie = newTrie(c.mergeRanks)
+	} else {
+		c.trie = newTrie(c.vocabulary)
+	}
+}
+
+func (c *Codec) rank(token string) (uint, bool) {
+	if c.useTrie {
+		c.trieOnce.Do(c.buildTrie)
+		return c.trie.rank(token)
+	}
+	if c.mergeRanks != nil {
+		rank, ok := c.mergeRanks[token]
+		return rank, ok
+	}
+	rank, ok := c.vocabulary[token]
+	return rank, ok
 }
 
 type part struct {
@@ -94,16 +917,31 @@ type part struct {
 }
 
 func (c *Codec) mergePairs(piece string) []part {
-	parts := make([]part, len(piece)+1)
-	for i := range len(parts) {
-		parts[i] = part{i, math.MaxUint}
+	return c.mergePairsInto(nil, piece, nil)
+}
+
+// mergePairsInto is mergePairs reusing the parts slice. If merged isn't nil
+// it is called with every pair in the order they are merged.
+func (c *Codec) mergePairsInto(parts []part, piece string, merged func(left, right string, rank uint)) []part {
+	parts = parts[:0]
+	if c.sentencePiece != nil {
+		// SentencePiece merges start from characters rather than bytes.
+		for i := range piece {
+			parts = append(parts, part{i, math.MaxUint})
+		}
+	} else {
+		parts = slices.Grow(parts, len(piece)+1)
+		for i := range len(piece) {
+			parts = append(parts, part{i, math.MaxUint})
+		}
 	}
+	parts = append(parts, part{len(piece), math.MaxUint})
 
 	getRank := func(index, skip int) uint {
 		if index+skip+2 < len(parts) {
 			start := parts[index].offset
 			end := parts[index+skip+2].offset
-			if rank, ok := c.vocabulary[piece[start:end]]; ok {
+			if rank, ok := c.rank(piece[start:end]); ok {
 				return rank
 			}
 		}
@@ -119,6 +957,7 @@ func (c *Codec) mergePairs(piece string) []part {
 			break
 		}
 
+		// the lowest rank wins, ties go to the leftmost pair as in tiktoken
 		minRank := uint(math.MaxUint)
 		minIndex := 0
 		for i, p := range parts[:len(parts)-1] {
@@ -131,6 +970,10 @@ func (c *Codec) mergePairs(piece string) []part {
 		if minRank == math.MaxUint {
 			break
 		}
+		if merged != nil {
+			start, mid, end := parts[minIndex].offset, parts[minIndex+1].offset, parts[minIndex+2].offset
+			merged(piece[start:mid], piece[mid:end], minRank)
+		}
 
 		parts[minIndex].rank = getRank(minIndex, 1)
 
@@ -0,0 +1,41 @@
+package codec
+
+// Encoder encodes text into caller provided slices, reusing its scratch
+// space between calls so a loop encoding many inputs barely allocates. An
+// Encoder is not safe for concurrent use, give every goroutine its own.
+type Encoder struct {
+	codec *Codec
+	parts []part
+	dst   []uint
+
+	// bound once so Encode doesn't allocate closures
+	splitFn func(string) error
+	yieldFn func(uint, string)
+}
+
+// NewEncoder returns an Encoder for the codec.
+func (c *Codec) NewEncoder() *Encoder {
+	e := &Encoder{codec: c}
+	e.splitFn = e.encodePiece
+	e.yieldFn = e.yield
+	return e
+}
+
+// Encode appends the token IDs of the input to dst and returns the extended
+// slice, like EncodeOrdinary.
+func (e *Encoder) Encode(input string, dst []uint) ([]uint, error) {
+	e.dst = dst
+	err := e.codec.split(input, e.splitFn)
+	dst, e.dst = e.dst, nil
+	return dst, err
+}
+
+func (e *Encoder) encodePiece(piece string) error {
+	var err error
+	e.parts, err = e.codec.encodePieceInto(e.parts, piece, e.yieldFn)
+	return err
+}
+
+func (e *Encoder) yield(id uint, _ string) {
+	e.dst = append(e.dst, id)
+}
@@ -0,0 +1,67 @@
+package codec_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestEncoder(t *testing.T) {
+	for _, c := range []*codec.Codec{codec.NewCl100kBase(), codec.NewGemmaBase()} {
+		t.Run(c.GetName(), func(t *testing.T) {
+			enc := c.NewEncoder()
+			var dst []uint
+			for _, text := range countTexts {
+				want, err := c.EncodeOrdinary(text)
+				if err != nil {
+					t.Fatalf("EncodeOrdinary(%q) error: %v", text, err)
+				}
+				dst, err = enc.Encode(text, dst[:0])
+				if err != nil {
+					t.Fatalf("Encode(%q) error: %v", text, err)
+				}
+				if !slices.Equal(dst, want) {
+					t.Errorf("Encode(%q) = %v, want %v", text, dst, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncoderAppends(t *testing.T) {
+	enc := codec.NewCl100kBase().NewEncoder()
+	ids, err := enc.Encode(" world", []uint{15339})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if want := []uint{15339, 1917}; !slices.Equal(ids, want) {
+		t.Errorf("Encode() = %v, want %v", ids, want)
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	c := codec.NewCl100kBase()
+	text := strings.Repeat("We know what we are, but know not what we may be. ", 20)
+
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := c.Encode(text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Encoder", func(b *testing.B) {
+		enc := c.NewEncoder()
+		var dst []uint
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var err error
+			if dst, err = enc.Encode(text, dst[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
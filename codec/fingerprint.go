@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"slices"
+	"strings"
+)
+
+// Fingerprint returns a hash of everything that decides how the codec
+// encodes: the vocabulary, the merge ranks, the split pattern and the
+// special tokens. It is stable across runs, so cached encodings can be keyed
+// by it and go stale when the vocabulary changes, e.g. after ReloadVocab.
+// The vocabulary is only hashed on the first call.
+func (c *Codec) Fingerprint() string {
+	c.fingerprintOnce.Do(func() {
+		h := sha256.New()
+		hashVocab(h, c.vocabulary)
+		hashVocab(h, c.mergeRanks)
+		c.vocabHash = h.Sum(nil)
+	})
+
+	h := sha256.New()
+	h.Write(c.vocabHash)
+	hashString(h, c.Pattern())
+	hashVocab(h, c.specialTokens)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// hashVocab writes the entries of the vocabulary to h in id order.
+func hashVocab[M ~map[string]uint](h hash.Hash, m M) {
+	words := make([]string, 0, len(m))
+	for word := range m {
+		words = append(words, word)
+	}
+	slices.SortFunc(words, func(a, b string) int {
+		return cmp.Or(cmp.Compare(m[a], m[b]), strings.Compare(a, b))
+	})
+
+	hashUint(h, uint64(len(words)))
+	for _, word := range words {
+		hashString(h, word)
+		hashUint(h, uint64(m[word]))
+	}
+}
+
+// hashString writes s to h with its length in front, so consecutive strings
+// can't run into each other.
+func hashString(h hash.Hash, s string) {
+	hashUint(h, uint64(len(s)))
+	h.Write([]byte(s))
+}
+
+func hashUint(h hash.Hash, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
@@ -0,0 +1,56 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Cleanup(func() { codec.ResetVocab("r50k_base") })
+
+	c := codec.NewR50kBase()
+	fingerprint := c.Fingerprint()
+	if len(fingerprint) != 32 {
+		t.Errorf("Fingerprint() = %q, want 32 hex digits", fingerprint)
+	}
+	if got := c.Fingerprint(); got != fingerprint {
+		t.Errorf("Fingerprint() = %q on the second call, want %q", got, fingerprint)
+	}
+	if got := codec.NewR50kBase().Fingerprint(); got != fingerprint {
+		t.Errorf("Fingerprint() = %q for another r50k_base codec, want %q", got, fingerprint)
+	}
+	// gpt2 has the same vocabulary, pattern and special tokens
+	if got := codec.NewGPT2Base().Fingerprint(); got != fingerprint {
+		t.Errorf("Fingerprint() = %q for gpt2, want %q", got, fingerprint)
+	}
+	for _, other := range []*codec.Codec{codec.NewP50kBase(), codec.NewCl100kBase(), codec.NewO200kBase()} {
+		if other.Fingerprint() == fingerprint {
+			t.Errorf("%s has the fingerprint of r50k_base", other.GetName())
+		}
+	}
+
+	if err := codec.ReloadVocab("r50k_base", strings.NewReader(testTiktoken("h", "e", "l", "o"))); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := codec.NewR50kBase().Fingerprint()
+	if reloaded == fingerprint {
+		t.Errorf("Fingerprint() didn't change after ReloadVocab")
+	}
+	if got := c.Fingerprint(); got != fingerprint {
+		t.Errorf("Fingerprint() of a codec created before ReloadVocab = %q, want %q", got, fingerprint)
+	}
+	codec.ResetVocab("r50k_base")
+	if got := codec.NewR50kBase().Fingerprint(); got != fingerprint {
+		t.Errorf("Fingerprint() = %q after ResetVocab, want %q", got, fingerprint)
+	}
+
+	clone := c.Clone()
+	if err := clone.AddSpecialToken("<|im_start|>", 50257); err != nil {
+		t.Fatal(err)
+	}
+	if clone.Fingerprint() == fingerprint {
+		t.Errorf("Fingerprint() didn't change after AddSpecialToken")
+	}
+}
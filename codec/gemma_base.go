@@ -0,0 +1,24 @@
+package codec
+
+// NewGemmaBase returns the Gemma SentencePiece tokenizer, which Gemini models
+// share. Unlike Llama 2, Gemma does not add a dummy prefix, so a leading word
+// is tokenized without the space marker.
+func NewGemmaBase(opts ...Option) *Codec {
+	gemmaVocabOnce.Do(gemmaVocabInit)
+
+	return withOptions(&Codec{
+		name:          "gemma",
+		vocabulary:    currentVocab("gemma", gemmaVocab),
+		pretokenizer:  mustRegexPretokenizer(sentencePiecePatStr),
+		sentencePiece: &sentencePiece{},
+		byteFallback:  true,
+		specialTokens: map[string]uint{
+			"<pad>":           0,
+			"<eos>":           1,
+			"<bos>":           2,
+			"<unk>":           3,
+			"<start_of_turn>": 106,
+			"<end_of_turn>":   107,
+		},
+	}, opts)
+}
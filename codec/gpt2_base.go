@@ -1,18 +1,15 @@
 package codec
 
-import (
-	"github.com/dlclark/regexp2"
-)
-
 const (
-	// GPT-2 pattern - simpler than Llama3, focuses on preserving whitespace differently
+	// gpt2PatStr is the pattern of the original GPT-2 encoder, which
+	// tiktoken's r50k_base and p50k_base use unchanged.
 	gpt2PatStr = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
 )
 
-func NewGPT2Base() *Codec {
-	gpt2BaseVocabOnce.Do(gpt2BaseVocabInit)
-
-	splitRegexp := regexp2.MustCompile(gpt2PatStr, regexp2.None)
+// NewGPT2Base returns the encoding of the original GPT-2 models. It is the
+// same as r50k_base and shares its embedded vocabulary.
+func NewGPT2Base(opts ...Option) *Codec {
+	r50kBaseVocabOnce.Do(r50kBaseVocabInit)
 
 	// Most GPT-2 models use these basic special tokens
 	specialTokens := map[string]uint{
@@ -20,10 +17,10 @@ func NewGPT2Base() *Codec {
 		// Modern models may add more, but this is the core one
 	}
 
-	return &Codec{
-		name:          "gpt2",
-		vocabulary:    gpt2BaseVocab,
-		splitRegexp:   splitRegexp,
-		specialTokens: specialTokens,
-	}
+	return New(Config{
+		Name:          "gpt2",
+		Vocabulary:    currentVocab("gpt2", r50kBaseVocab),
+		Pattern:       gpt2PatStr,
+		SpecialTokens: specialTokens,
+	}, opts...)
 }
@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"cmp"
+	"slices"
+)
+
+// TokenFrequency is how often a token occurs, as reported by TopTokens.
+type TokenFrequency struct {
+	ID    uint
+	Token string
+	Count int
+}
+
+// Histogram encodes the texts like Encode and returns how often each token
+// id occurs across all of them.
+func (c *Codec) Histogram(texts []string) (map[uint]int, error) {
+	histogram := make(map[uint]int)
+	for _, text := range texts {
+		err := c.tokenize(text, func(id uint, _ string) {
+			histogram[id]++
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return histogram, nil
+}
+
+// TopTokens returns the n most frequent tokens of a histogram with their
+// decoded text, most frequent first and by id on ties. It fails on ids the
+// codec doesn't know.
+func (c *Codec) TopTokens(histogram map[uint]int, n int) ([]TokenFrequency, error) {
+	top := make([]TokenFrequency, 0, len(histogram))
+	for id, count := range histogram {
+		top = append(top, TokenFrequency{ID: id, Count: count})
+	}
+	slices.SortFunc(top, func(a, b TokenFrequency) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.ID, b.ID))
+	})
+	top = top[:min(n, len(top))]
+
+	for i := range top {
+		token, err := c.decodeToken(top[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		top[i].Token = token
+	}
+	return top, nil
+}
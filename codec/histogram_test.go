@@ -0,0 +1,54 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestHistogram(t *testing.T) {
+	c := codec.NewCl100kBase()
+	texts := []string{
+		"the cat sat on the mat",
+		"the dog ate the bone",
+		"a bird in the hand",
+	}
+
+	histogram, err := c.Histogram(texts)
+	if err != nil {
+		t.Fatalf("Histogram() error: %v", err)
+	}
+	total := 0
+	for _, count := range histogram {
+		total += count
+	}
+	want := 0
+	for _, text := range texts {
+		n, _ := c.Count(text)
+		want += n
+	}
+	if total != want {
+		t.Errorf("Histogram() counts %d tokens, want %d", total, want)
+	}
+
+	top, err := c.TopTokens(histogram, 2)
+	if err != nil {
+		t.Fatalf("TopTokens() error: %v", err)
+	}
+	if len(top) != 2 {
+		t.Fatalf("TopTokens() returned %d tokens, want 2", len(top))
+	}
+	if top[0].Token != " the" || top[0].Count != 3 {
+		t.Errorf("TopTokens()[0] = %+v, want \" the\" 3 times", top[0])
+	}
+	if top[1].Token != "the" || top[1].Count != 2 {
+		t.Errorf("TopTokens()[1] = %+v, want \"the\" 2 times", top[1])
+	}
+
+	if top, _ := c.TopTokens(histogram, 1000); len(top) != len(histogram) {
+		t.Errorf("TopTokens() returned %d tokens, want all %d", len(top), len(histogram))
+	}
+	if _, err := c.TopTokens(map[uint]int{1 << 30: 1}, 1); err == nil {
+		t.Error("TopTokens() expected error for an unknown id")
+	}
+}
@@ -0,0 +1,272 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type hfTokenizer struct {
+	AddedTokens []struct {
+		ID      uint   `json:"id"`
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+	Normalizer   *hfNormalizer   `json:"normalizer"`
+	PreTokenizer *hfPreTokenizer `json:"pre_tokenizer"`
+	Model        struct {
+		Type         string            `json:"type"`
+		Vocab        map[string]uint   `json:"vocab"`
+		Merges       []json.RawMessage `json:"merges"`
+		ByteFallback bool              `json:"byte_fallback"`
+	} `json:"model"`
+}
+
+type hfNormalizer struct {
+	Type        string         `json:"type"`
+	Normalizers []hfNormalizer `json:"normalizers"`
+	Prepend     string         `json:"prepend"`
+	Pattern     hfPattern      `json:"pattern"`
+	Content     string         `json:"content"`
+}
+
+type hfPreTokenizer struct {
+	Type           string           `json:"type"`
+	PreTokenizers  []hfPreTokenizer `json:"pretokenizers"`
+	Pattern        hfPattern        `json:"pattern"`
+	AddPrefixSpace bool             `json:"add_prefix_space"`
+	PrependScheme  string           `json:"prepend_scheme"`
+	UseRegex       *bool            `json:"use_regex"`
+}
+
+type hfPattern struct {
+	Regex  string `json:"Regex"`
+	String string `json:"String"`
+}
+
+// hfOptions collects what the normalizer and pre-tokenizer sections mean for
+// the codec.
+type hfOptions struct {
+	pattern       string
+	byteLevel     bool
+	sentencePiece *sentencePiece
+}
+
+// LoadHuggingFace builds a codec from a HuggingFace tokenizer.json holding a
+// BPE model. Merge ranks come from the order of model.merges and every entry
+// of added_tokens is registered as a special token. Byte level vocabularies
+// (GPT-2 style) and SentencePiece style vocabularies using the ▁ marker are
+// supported, other normalizers such as NFC are not applied. Models with
+// byte_fallback set encode missing characters as <0xXX> byte tokens.
+func LoadHuggingFace(r io.Reader) (*Codec, error) {
+	var hf hfTokenizer
+	if err := json.NewDecoder(r).Decode(&hf); err != nil {
+		return nil, fmt.Errorf("error decoding tokenizer.json: %v", err)
+	}
+	if hf.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported model type: %q", hf.Model.Type)
+	}
+
+	var opts hfOptions
+	if err := opts.applyNormalizer(hf.Normalizer); err != nil {
+		return nil, err
+	}
+	if err := opts.applyPreTokenizer(hf.PreTokenizer); err != nil {
+		return nil, err
+	}
+	if opts.pattern == "" {
+		return nil, fmt.Errorf("no pre-tokenizer pattern found")
+	}
+
+	pretokenizer, err := NewRegexPretokenizer(opts.pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	specialTokens := make(map[string]uint, len(hf.AddedTokens))
+	for _, t := range hf.AddedTokens {
+		specialTokens[t.Content] = t.ID
+	}
+
+	decode := func(token string) (string, bool) { return token, true }
+	if opts.byteLevel {
+		decode = decodeByteLevel
+	}
+
+	vocabulary := make(vocab, len(hf.Model.Vocab))
+	for token, id := range hf.Model.Vocab {
+		word, ok := decode(token)
+		if !ok {
+			// outside the byte level alphabet, so BPE can never produce it
+			continue
+		}
+		vocabulary[word] = id
+	}
+
+	mergeRanks := make(vocab, len(hf.Model.Merges))
+	for i, raw := range hf.Model.Merges {
+		left, right, err := parseMerge(raw)
+		if err != nil {
+			return nil, fmt.Errorf("merge %d: %v", i, err)
+		}
+		merged, ok := decode(left + right)
+		if !ok {
+			return nil, fmt.Errorf("merge %d: invalid byte level token: %q", i, left+right)
+		}
+		if _, ok := mergeRanks[merged]; !ok {
+			mergeRanks[merged] = uint(i)
+		}
+	}
+
+	return &Codec{
+		name:          "huggingface",
+		vocabulary:    vocabulary,
+		mergeRanks:    mergeRanks,
+		pretokenizer:  pretokenizer,
+		specialTokens: specialTokens,
+		sentencePiece: opts.sentencePiece,
+		byteFallback:  hf.Model.ByteFallback,
+	}, nil
+}
+
+func (o *hfOptions) applyNormalizer(n *hfNormalizer) error {
+	if n == nil {
+		return nil
+	}
+	switch n.Type {
+	case "Sequence":
+		for i := range n.Normalizers {
+			if err := o.applyNormalizer(&n.Normalizers[i]); err != nil {
+				return err
+			}
+		}
+	case "Prepend":
+		if n.Prepend == spaceMarker {
+			o.useSentencePiece().addDummyPrefix = true
+		}
+	case "Replace":
+		if n.Pattern.String == " " && n.Content == spaceMarker {
+			o.useSentencePiece()
+		}
+	}
+	return nil
+}
+
+func (o *hfOptions) applyPreTokenizer(p *hfPreTokenizer) error {
+	if p == nil {
+		return nil
+	}
+	switch p.Type {
+	case "Sequence":
+		for i := range p.PreTokenizers {
+			if err := o.applyPreTokenizer(&p.PreTokenizers[i]); err != nil {
+				return err
+			}
+		}
+	case "ByteLevel":
+		if p.AddPrefixSpace {
+			return fmt.Errorf("unsupported pre-tokenizer option: add_prefix_space")
+		}
+		o.byteLevel = true
+		if p.UseRegex == nil || *p.UseRegex {
+			return o.setPattern(gpt2PatStr)
+		}
+	case "Split":
+		if p.Pattern.Regex == "" {
+			return fmt.Errorf("unsupported Split pre-tokenizer without a regex")
+		}
+		return o.setPattern(p.Pattern.Regex)
+	case "Metaspace":
+		sp := o.useSentencePiece()
+		sp.addDummyPrefix = p.AddPrefixSpace || p.PrependScheme == "always" || p.PrependScheme == "first"
+	default:
+		return fmt.Errorf("unsupported pre-tokenizer: %q", p.Type)
+	}
+	return nil
+}
+
+func (o *hfOptions) setPattern(pattern string) error {
+	if o.pattern != "" && o.pattern != pattern {
+		return fmt.Errorf("unsupported pre-tokenizer: more than one split pattern")
+	}
+	o.pattern = pattern
+	return nil
+}
+
+func (o *hfOptions) useSentencePiece() *sentencePiece {
+	if o.sentencePiece == nil {
+		o.sentencePiece = &sentencePiece{}
+		o.pattern = sentencePiecePatStr
+	}
+	return o.sentencePiece
+}
+
+// parseMerge reads a merge written either as "left right" or, in newer
+// tokenizer.json files, as ["left", "right"].
+func parseMerge(raw json.RawMessage) (string, string, error) {
+	var pair []string
+	if err := json.Unmarshal(raw, &pair); err == nil {
+		if len(pair) != 2 {
+			return "", "", fmt.Errorf("invalid merge: %s", raw)
+		}
+		return pair[0], pair[1], nil
+	}
+
+	var merge string
+	if err := json.Unmarshal(raw, &merge); err != nil {
+		return "", "", fmt.Errorf("invalid merge: %s", raw)
+	}
+	left, right, ok := strings.Cut(merge, " ")
+	if !ok {
+		return "", "", fmt.Errorf("invalid merge: %q", merge)
+	}
+	return left, right, nil
+}
+
+// byteLevelDecoder maps the printable characters GPT-2 style byte level
+// vocabularies use back to the bytes they stand for, e.g. "Ġ" is a space.
+var byteLevelDecoder = func() map[rune]byte {
+	decoder := make(map[rune]byte, 256)
+	n := 0
+	for b := 0; b < 256; b++ {
+		switch {
+		case b >= '!' && b <= '~', b >= 0xA1 && b <= 0xAC, b >= 0xAE && b <= 0xFF:
+			decoder[rune(b)] = byte(b)
+		default:
+			decoder[rune(256+n)] = byte(b)
+			n++
+		}
+	}
+	return decoder
+}()
+
+// byteLevelEncoder is the inverse of byteLevelDecoder.
+var byteLevelEncoder = func() [256]rune {
+	var encoder [256]rune
+	for r, b := range byteLevelDecoder {
+		encoder[b] = r
+	}
+	return encoder
+}()
+
+// encodeByteLevel maps every byte of the token to the character byte level
+// vocabularies use for it.
+func encodeByteLevel(token string) string {
+	var sb strings.Builder
+	for i := 0; i < len(token); i++ {
+		sb.WriteRune(byteLevelEncoder[token[i]])
+	}
+	return sb.String()
+}
+
+func decodeByteLevel(token string) (string, bool) {
+	out := make([]byte, 0, len(token))
+	for _, r := range token {
+		b, ok := byteLevelDecoder[r]
+		if !ok {
+			return "", false
+		}
+		out = append(out, b)
+	}
+	return string(out), true
+}
@@ -0,0 +1,66 @@
+package codec_test
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestLoadHuggingFace(t *testing.T) {
+	f, err := os.Open("testdata/tokenizer.json")
+	if err != nil {
+		t.Fatalf("can't open fixture: %v", err)
+	}
+	defer f.Close()
+
+	tok, err := codec.LoadHuggingFace(f)
+	if err != nil {
+		t.Fatalf("can't load tokenizer: %v", err)
+	}
+
+	// "el" has a lower id than "he" but is the last merge, so "hel" only
+	// comes out right when ranks are taken from the merges
+	tests := []struct {
+		text   string
+		ids    []uint
+		tokens []string
+	}{
+		{text: "hello world", ids: []uint{17, 12}, tokens: []string{"hello", " world"}},
+		{text: "hello\nworld", ids: []uint{17, 9, 7, 15, 13}, tokens: []string{"hello", "\n", "w", "or", "ld"}},
+		{text: "held", ids: []uint{20, 13}, tokens: []string{"he", "ld"}},
+		{text: "hel", ids: []uint{20, 4}, tokens: []string{"he", "l"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			ids, tokens, err := tok.Encode(test.text)
+			if err != nil {
+				t.Fatalf("error encoding: %v", err)
+			}
+			if !slices.Equal(ids, test.ids) {
+				t.Errorf("encoding mismatch - want: %v got: %v", test.ids, ids)
+			}
+			if !slices.Equal(tokens, test.tokens) {
+				t.Errorf("tokens mismatch - want: %q got: %q", test.tokens, tokens)
+			}
+
+			text, err := tok.Decode(ids)
+			if err != nil {
+				t.Fatalf("error decoding: %v", err)
+			}
+			if text != test.text {
+				t.Errorf("decoding mismatch - want: %q got: %q", test.text, text)
+			}
+		})
+	}
+}
+
+func TestLoadHuggingFaceUnsupported(t *testing.T) {
+	_, err := codec.LoadHuggingFace(strings.NewReader(`{"model": {"type": "WordPiece"}}`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported model type") {
+		t.Errorf("want unsupported model type error, got: %v", err)
+	}
+}
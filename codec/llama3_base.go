@@ -1,10 +1,6 @@
 package codec
 
-import (
-	"fmt"
-
-	"github.com/dlclark/regexp2"
-)
+import "fmt"
 
 const (
 	numReservedSpecialTokens = 256
@@ -12,12 +8,11 @@ const (
 	llamaPatStr = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
 )
 
-func NewLLama3Base() *Codec {
+func NewLLama3Base(opts ...Option) *Codec {
 	llamaVocabOnce.Do(llamaVocabInit)
 
-	splitRegexp := regexp2.MustCompile(llamaPatStr, regexp2.None)
-
-	numBaseTokens := len(llamaVocab)
+	vocabulary := currentVocab("llama", llamaVocab)
+	numBaseTokens := len(vocabulary)
 
 	specialTokens := map[string]uint{
 		"<|begin_of_text|>":            uint(numBaseTokens),
@@ -40,10 +35,12 @@ func NewLLama3Base() *Codec {
 		specialTokens[tokenName] = uint(numBaseTokens + definedSpecialTokens + i)
 	}
 
-	return &Codec{
-		name:          "llama",
-		vocabulary:    llamaVocab,
-		splitRegexp:   splitRegexp,
-		specialTokens: specialTokens,
-	}
+	c := New(Config{
+		Name:          "llama",
+		Vocabulary:    vocabulary,
+		Pattern:       llamaPatStr,
+		SpecialTokens: specialTokens,
+	}, opts...)
+	c.chatTemplate = llama3Chat
+	return c
 }
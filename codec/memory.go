@@ -0,0 +1,33 @@
+package codec
+
+import "unsafe"
+
+// mapEntryBytes approximates what a map entry with a string key costs beyond
+// the string data: the string header, an 8 byte value and the slack of a
+// map that is not completely full.
+const mapEntryBytes = 40
+
+// ApproxMemoryBytes estimates the memory held by the codec's vocabulary, the
+// merge ranks, the special tokens, and the reverse vocabulary and trie if
+// they have been built. Codecs of the same built-in encoding share one
+// vocabulary, so it is only held once however many of them there are. The
+// estimate must not be taken while the codec is in use by other goroutines.
+func (c *Codec) ApproxMemoryBytes() int {
+	size := vocabBytes(c.vocabulary) + vocabBytes(c.mergeRanks) + vocabBytes(c.specialTokens)
+	if c.reverseVocabulary != nil {
+		// the strings are shared with the vocabulary
+		size += len(c.reverseVocabulary) * mapEntryBytes
+	}
+	if c.trie != nil {
+		size += len(c.trie.nodes)*int(unsafe.Sizeof(trieNode{})) + len(c.trie.labels) + len(c.trie.targets)*4
+	}
+	return size
+}
+
+func vocabBytes[M ~map[string]uint](m M) int {
+	size := len(m) * mapEntryBytes
+	for k := range m {
+		size += len(k)
+	}
+	return size
+}
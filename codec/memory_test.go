@@ -0,0 +1,25 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestApproxMemoryBytes(t *testing.T) {
+	small := codec.NewR50kBase().ApproxMemoryBytes()
+	for _, c := range []*codec.Codec{codec.NewO200kBase(), codec.NewGemmaBase()} {
+		if size := c.ApproxMemoryBytes(); size < 3*small {
+			t.Errorf("%s: ApproxMemoryBytes() = %d, want at least 3x the %d of r50k_base", c.GetName(), size, small)
+		}
+	}
+
+	c := codec.NewCl100kBase()
+	before := c.ApproxMemoryBytes()
+	if _, err := c.Decode([]uint{15339}); err != nil {
+		t.Fatal(err)
+	}
+	if after := c.ApproxMemoryBytes(); after <= before {
+		t.Errorf("ApproxMemoryBytes() = %d after decoding, want more than %d", after, before)
+	}
+}
@@ -0,0 +1,21 @@
+package codec
+
+// NewMistralBase returns the SentencePiece tokenizer of the first Mistral
+// models, Mistral 7B and Mixtral 8x7B, with its 32k vocabulary. Like Llama
+// 2 it adds a dummy prefix. Later models moved to Tekken, a byte-level BPE.
+func NewMistralBase(opts ...Option) *Codec {
+	mistralVocabOnce.Do(mistralVocabInit)
+
+	return withOptions(&Codec{
+		name:          "mistral",
+		vocabulary:    currentVocab("mistral", mistralVocab),
+		pretokenizer:  mustRegexPretokenizer(sentencePiecePatStr),
+		sentencePiece: &sentencePiece{addDummyPrefix: true},
+		byteFallback:  true,
+		specialTokens: map[string]uint{
+			"<unk>": 0,
+			"<s>":   1,
+			"</s>":  2,
+		},
+	}, opts)
+}
@@ -0,0 +1,80 @@
+package codec
+
+import "time"
+
+// Option configures a Codec when it is created.
+type Option func(*Codec)
+
+// WithTrie looks up merge ranks in a byte trie instead of the vocabulary map.
+// The trie is built from the vocabulary on first use.
+func WithTrie() Option {
+	return func(c *Codec) {
+		c.useTrie = true
+	}
+}
+
+// WithByteFallback encodes parts of the input missing from the vocabulary as
+// <0xXX> byte tokens, as SentencePiece vocabularies with byte fallback do,
+// instead of failing.
+func WithByteFallback() Option {
+	return func(c *Codec) {
+		c.byteFallback = true
+	}
+}
+
+// WithMatchTimeout limits the time the split pattern may take to find a
+// single piece, so input that makes the regexp backtrack pathologically fails
+// with ErrMatchTimeout instead of blocking. There is no limit by default.
+// All-ASCII input split without the regexp is never limited, as that takes
+// linear time. It has no effect on codecs that don't split with a
+// RegexPretokenizer.
+func WithMatchTimeout(timeout time.Duration) Option {
+	return func(c *Codec) {
+		if p, ok := c.pretokenizer.(*RegexPretokenizer); ok {
+			p.re.MatchTimeout = timeout
+		}
+	}
+}
+
+// WithPretokenizer splits the input with p instead of the codec's pattern.
+func WithPretokenizer(p Pretokenizer) Option {
+	return func(c *Codec) {
+		c.pretokenizer = p
+	}
+}
+
+// InvalidUTF8 selects how a codec handles input that isn't valid UTF-8.
+type InvalidUTF8 int
+
+const (
+	// InvalidUTF8Keep encodes invalid bytes as they are, so they decode back
+	// unchanged. This is the default.
+	InvalidUTF8Keep InvalidUTF8 = iota
+	// InvalidUTF8Replace replaces each run of invalid bytes with U+FFFD.
+	InvalidUTF8Replace
+	// InvalidUTF8Error makes encoding fail on invalid bytes.
+	InvalidUTF8Error
+)
+
+// WithInvalidUTF8 sets how invalid UTF-8 in the input is handled.
+func WithInvalidUTF8(mode InvalidUTF8) Option {
+	return func(c *Codec) {
+		c.invalidUTF8 = mode
+	}
+}
+
+// WithNormalizer runs normalize over the input before it is split, e.g.
+// norm.NFC.String from golang.org/x/text/unicode/norm, so composed and
+// decomposed forms of the same text count the same.
+func WithNormalizer(normalize func(string) string) Option {
+	return func(c *Codec) {
+		c.normalize = normalize
+	}
+}
+
+func withOptions(c *Codec, opts []Option) *Codec {
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
@@ -0,0 +1,108 @@
+package codec_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestWithInvalidUTF8(t *testing.T) {
+	input := "caf\xe9 ok"
+
+	ids, _, err := codec.NewCl100kBase(codec.WithInvalidUTF8(codec.InvalidUTF8Replace)).Encode(input)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	text, err := codec.NewCl100kBase().Decode(ids)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if want := "caf� ok"; text != want {
+		t.Fatalf("decoded %q, want %q", text, want)
+	}
+
+	if _, err := codec.NewCl100kBase(codec.WithInvalidUTF8(codec.InvalidUTF8Error)).Count(input); err == nil {
+		t.Fatalf("Count() expected an invalid UTF-8 error")
+	}
+}
+
+func TestWithNormalizer(t *testing.T) {
+	composed := "Café crème brûlée"
+	decomposed := "Café crème brûlée"
+
+	// a stand-in for norm.NFC.String covering the characters above
+	nfc := strings.NewReplacer("é", "é", "è", "è", "û", "û").Replace
+
+	plain := codec.NewCl100kBase()
+	a, _ := plain.Count(composed)
+	b, _ := plain.Count(decomposed)
+	if a == b {
+		t.Fatalf("expected different counts without normalization, got %d", a)
+	}
+
+	normalized := codec.NewCl100kBase(codec.WithNormalizer(nfc))
+	a, _ = normalized.Count(composed)
+	b, _ = normalized.Count(decomposed)
+	if a != b {
+		t.Fatalf("normalized counts differ: %d and %d", a, b)
+	}
+}
+
+func TestWithByteFallback(t *testing.T) {
+	vocab := map[string]uint{"a": 0, " ": 1, "<0xE2>": 2, "<0x98>": 3, "<0x83>": 4}
+	cfg := codec.Config{Name: "tiny", Vocabulary: vocab, Pattern: `\S+|\s+`}
+
+	// ☃ is not in the vocabulary, only its bytes are
+	input := "a ☃"
+	c := codec.New(cfg, codec.WithByteFallback())
+	ids, tokens, err := c.Encode(input)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if want := []uint{0, 1, 2, 3, 4}; !slices.Equal(ids, want) {
+		t.Errorf("encoding mismatch - want: %v got: %v (%q)", want, ids, tokens)
+	}
+	text, err := c.Decode(ids)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if text != input {
+		t.Errorf("decoded %q, want %q", text, input)
+	}
+	if count, _ := c.Count(input); count != len(ids) {
+		t.Errorf("Count() = %d, want %d", count, len(ids))
+	}
+}
+
+func TestWithMatchTimeout(t *testing.T) {
+	// (a+)+$ backtracks exponentially on a run of a's that doesn't end the
+	// input
+	c := codec.New(codec.Config{
+		Name:       "pathological",
+		Vocabulary: map[string]uint{"a": 0, "é": 1},
+		Pattern:    `(a+)+$|é`,
+	}, codec.WithMatchTimeout(10*time.Millisecond))
+	input := strings.Repeat("a", 40) + "é"
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Count(input)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, codec.ErrMatchTimeout) {
+			t.Errorf("Count() error = %v, want ErrMatchTimeout", err)
+		}
+		if err != nil && strings.Contains(err.Error(), input) {
+			t.Errorf("Count() error quotes the input: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Count() didn't time out")
+	}
+}
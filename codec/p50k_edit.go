@@ -1,21 +1,17 @@
 package codec
 
-import "github.com/dlclark/regexp2"
-
-func NewP50kEdit() *Codec {
+func NewP50kEdit(opts ...Option) *Codec {
 	p50kBaseVocabOnce.Do(p50kBaseVocabInit)
 
-	splitRegexp := regexp2.MustCompile(`'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`, regexp2.None)
-
-	return &Codec{
-		name:        "p50k_edit",
-		vocabulary:  p50kBaseVocab,
-		splitRegexp: splitRegexp,
+	return withOptions(&Codec{
+		name:         "p50k_edit",
+		vocabulary:   currentVocab("p50k_edit", p50kBaseVocab),
+		pretokenizer: mustRegexPretokenizer(`'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`),
 		specialTokens: map[string]uint{
 			"<|endoftext|>":  50256,
 			"<|fim_prefix|>": 50281,
 			"<|fim_middle|>": 50282,
 			"<|fim_suffix|>": 50283,
 		},
-	}
+	}, opts)
 }
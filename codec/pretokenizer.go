@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Pretokenizer splits the input into the pieces that are encoded one at a
+// time, before BPE or WordPiece runs on them.
+type Pretokenizer interface {
+	// Split calls fn with every piece of the text in order, together with
+	// its byte offset in the text, and stops at the first error fn returns.
+	Split(text string, fn func(piece string, offset int) error) error
+}
+
+// RegexPretokenizer splits text into the matches of a regular expression,
+// as tiktoken does. It is what every built-in codec uses.
+type RegexPretokenizer struct {
+	re *regexp2.Regexp
+}
+
+// NewRegexPretokenizer compiles the pattern into a RegexPretokenizer.
+func NewRegexPretokenizer(pattern string) (*RegexPretokenizer, error) {
+	re, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+	return &RegexPretokenizer{re: re}, nil
+}
+
+// mustRegexPretokenizer is NewRegexPretokenizer for patterns that are known
+// to compile.
+func mustRegexPretokenizer(pattern string) *RegexPretokenizer {
+	return &RegexPretokenizer{re: regexp2.MustCompile(pattern, regexp2.None)}
+}
+
+// Pattern returns the regular expression the text is split with.
+func (p *RegexPretokenizer) Pattern() string {
+	return p.re.String()
+}
+
+// Split calls fn with every match of the pattern. All-ASCII text is split
+// without the regexp when the pattern is one of the built-in ones.
+func (p *RegexPretokenizer) Split(text string, fn func(piece string, offset int) error) error {
+	if next := asciiSplitters[p.re.String()]; next != nil && isASCII(text) {
+		return splitASCII(text, next, fn)
+	}
+
+	// regexp2 matches on runes and turns invalid UTF-8 into U+FFFD, walk the
+	// text along the matches to map them back to the input bytes so nothing
+	// is lost.
+	pos, runes := 0, 0
+	advance := func(to int) {
+		for ; runes < to; runes++ {
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			pos += size
+		}
+	}
+
+	match, err := p.re.FindStringMatch(text)
+	if err != nil {
+		return p.matchError(err)
+	}
+	for match != nil {
+		advance(match.Index)
+		start := pos
+		advance(match.Index + match.Length)
+		if err := fn(text[start:pos], start); err != nil {
+			return err
+		}
+		match, err = p.re.FindNextMatch(match)
+		if err != nil {
+			return p.matchError(err)
+		}
+	}
+
+	return nil
+}
+
+func (p *RegexPretokenizer) matchError(err error) error {
+	if p.re.MatchTimeout != regexp2.DefaultMatchTimeout {
+		return fmt.Errorf("%w after %v", ErrMatchTimeout, p.re.MatchTimeout)
+	}
+	return fmt.Errorf("error matching: %v", err)
+}
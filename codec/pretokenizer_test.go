@@ -0,0 +1,130 @@
+package codec_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+	"github.com/dlclark/regexp2"
+)
+
+// regexpPieces splits text into the matches of pattern with regexp2 alone.
+func regexpPieces(t *testing.T, pattern, text string) []string {
+	t.Helper()
+	re := regexp2.MustCompile(pattern, regexp2.None)
+	runes := []rune(text)
+	var pieces []string
+	match, err := re.FindStringMatch(text)
+	for ; match != nil; match, err = re.FindNextMatch(match) {
+		pieces = append(pieces, string(runes[match.Index:match.Index+match.Length]))
+	}
+	if err != nil {
+		t.Fatalf("error matching %q: %v", text, err)
+	}
+	return pieces
+}
+
+func splitAll(t *testing.T, p codec.Pretokenizer, text string) []string {
+	t.Helper()
+	var pieces []string
+	err := p.Split(text, func(piece string, offset int) error {
+		if text[offset:offset+len(piece)] != piece {
+			t.Errorf("piece %q at %d is %q in the text", piece, offset, text[offset:offset+len(piece)])
+		}
+		pieces = append(pieces, piece)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error splitting %q: %v", text, err)
+	}
+	return pieces
+}
+
+func TestRegexPretokenizer(t *testing.T) {
+	texts := append(slices.Clone(countTexts),
+		"hello world",
+		"  leading and trailing  ",
+		"line\nbreaks\r\n\r\nand  \n  indented\n\n",
+		"func main() {\n\tfmt.Println(\"hi\")\n}\n",
+		"caf\xc3 \xff\xfe bytes",
+	)
+
+	for _, c := range roundTripCodecs() {
+		p, err := codec.NewRegexPretokenizer(c.Pattern())
+		if err != nil {
+			t.Fatalf("%s: %v", c.GetName(), err)
+		}
+		if p.Pattern() != c.Pattern() {
+			t.Errorf("%s: Pattern() = %q, want %q", c.GetName(), p.Pattern(), c.Pattern())
+		}
+		for _, text := range texts {
+			got := splitAll(t, p, text)
+			if !utf8.ValidString(text) {
+				// invalid UTF-8 is matched as U+FFFD but handed out as the
+				// input bytes
+				if joined := strings.Join(got, ""); joined != text {
+					t.Errorf("%s: Split(%q) joins to %q", c.GetName(), text, joined)
+				}
+				continue
+			}
+			want := regexpPieces(t, c.Pattern(), text)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: Split(%q) = %q, want %q", c.GetName(), text, got, want)
+			}
+		}
+	}
+
+	if _, err := codec.NewRegexPretokenizer(`(`); err == nil {
+		t.Errorf("NewRegexPretokenizer accepted an invalid pattern")
+	}
+}
+
+// fieldsPretokenizer splits text into words and the spaces before them.
+type fieldsPretokenizer struct{}
+
+func (fieldsPretokenizer) Split(text string, fn func(piece string, offset int) error) error {
+	start := 0
+	for i := 1; i <= len(text); i++ {
+		if i == len(text) || text[i] == ' ' {
+			if err := fn(text[start:i], start); err != nil {
+				return err
+			}
+			start = i
+		}
+	}
+	return nil
+}
+
+func TestWithPretokenizer(t *testing.T) {
+	text := "hello world, how are you?"
+	c := codec.NewCl100kBase(codec.WithPretokenizer(fieldsPretokenizer{}))
+	if got := c.Pattern(); got != "" {
+		t.Errorf("Pattern() = %q, want \"\"", got)
+	}
+
+	ids, _, err := c.Encode(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []uint
+	for _, piece := range splitAll(t, fieldsPretokenizer{}, text) {
+		pieceIDs, _, err := codec.NewCl100kBase().Encode(piece)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, pieceIDs...)
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("Encode(%q) = %v, want %v", text, ids, want)
+	}
+
+	decoded, err := c.Decode(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != text {
+		t.Errorf("Decode = %q, want %q", decoded, text)
+	}
+}
@@ -1,18 +1,14 @@
 package codec
 
-import "github.com/dlclark/regexp2"
-
-func NewR50kBase() *Codec {
+func NewR50kBase(opts ...Option) *Codec {
 	r50kBaseVocabOnce.Do(r50kBaseVocabInit)
 
-	splitRegexp := regexp2.MustCompile(`'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`, regexp2.None)
-
-	return &Codec{
-		name:        "r50k_base",
-		vocabulary:  r50kBaseVocab,
-		splitRegexp: splitRegexp,
-		specialTokens: map[string]uint{
+	return New(Config{
+		Name:       "r50k_base",
+		Vocabulary: currentVocab("r50k_base", r50kBaseVocab),
+		Pattern:    gpt2PatStr,
+		SpecialTokens: map[string]uint{
 			"<|endoftext|>": 50256,
 		},
-	}
+	}, opts...)
 }
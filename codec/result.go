@@ -0,0 +1,59 @@
+package codec
+
+import "encoding/json"
+
+// EncodedResult is an encoded text that can be stored and decoded later,
+// e.g. in a cache shared between processes.
+type EncodedResult struct {
+	// Encoding is the name of the codec the text was encoded with.
+	Encoding string
+	IDs      []uint
+	// Tokens are optional.
+	Tokens []string
+}
+
+// encodedResultJSON is the JSON form of an EncodedResult. Tokens of byte
+// level encodings can hold partial UTF-8 sequences, which JSON strings can't
+// represent, so they are stored as base64 bytes.
+type encodedResultJSON struct {
+	Encoding string   `json:"encoding"`
+	IDs      []uint   `json:"ids"`
+	Tokens   [][]byte `json:"tokens,omitempty"`
+}
+
+func (r EncodedResult) MarshalJSON() ([]byte, error) {
+	out := encodedResultJSON{Encoding: r.Encoding, IDs: r.IDs}
+	if out.IDs == nil {
+		out.IDs = []uint{}
+	}
+	if r.Tokens != nil {
+		out.Tokens = make([][]byte, len(r.Tokens))
+		for i, token := range r.Tokens {
+			out.Tokens[i] = []byte(token)
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (r *EncodedResult) UnmarshalJSON(data []byte) error {
+	var in encodedResultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = EncodedResult{Encoding: in.Encoding, IDs: in.IDs}
+	if in.Tokens != nil {
+		r.Tokens = make([]string, len(in.Tokens))
+		for i, token := range in.Tokens {
+			r.Tokens[i] = string(token)
+		}
+	}
+	return nil
+}
+
+// EncodeResult encodes the text like Encode and returns the ids and tokens
+// together with the codec name. On error the result holds the partial ids
+// and tokens Encode returns.
+func (c *Codec) EncodeResult(text string) (EncodedResult, error) {
+	ids, tokens, err := c.Encode(text)
+	return EncodedResult{Encoding: c.name, IDs: ids, Tokens: tokens}, err
+}
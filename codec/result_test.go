@@ -0,0 +1,61 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestEncodedResultJSON(t *testing.T) {
+	c := codec.NewCl100kBase()
+	// the emoji is split into tokens that aren't valid UTF-8 on their own
+	text := "Hello, 世界 👋🏽"
+
+	result, err := c.EncodeResult(text)
+	if err != nil {
+		t.Fatalf("EncodeResult() error: %v", err)
+	}
+	if result.Encoding != "cl100k_base" {
+		t.Errorf("Encoding = %q, want cl100k_base", result.Encoding)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"encoding":"cl100k_base","ids":[`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	var decoded codec.EncodedResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if decoded.Encoding != result.Encoding || !slices.Equal(decoded.IDs, result.IDs) || !slices.Equal(decoded.Tokens, result.Tokens) {
+		t.Errorf("round trip mismatch - want: %+v got: %+v", result, decoded)
+	}
+
+	got, err := c.Decode(decoded.IDs)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if got != text {
+		t.Errorf("decoded %q, want %q", got, text)
+	}
+	if strings.Join(decoded.Tokens, "") != text {
+		t.Errorf("tokens join to %q, want %q", strings.Join(decoded.Tokens, ""), text)
+	}
+}
+
+func TestEncodedResultJSONWithoutTokens(t *testing.T) {
+	data, err := json.Marshal(codec.EncodedResult{Encoding: "r50k_base"})
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if want := `{"encoding":"r50k_base","ids":[]}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,82 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+// roundTripCodecs are the built-in codecs whose Decode gives back the input.
+// WordPiece lowercases and drops the original spacing, so BERT isn't one.
+func roundTripCodecs() []*codec.Codec {
+	return []*codec.Codec{
+		codec.NewR50kBase(),
+		codec.NewP50kBase(),
+		codec.NewP50kEdit(),
+		codec.NewCl100kBase(),
+		codec.NewO200kBase(),
+		codec.NewLLama3Base(),
+		codec.NewAnthropicBase(),
+		codec.NewGemmaBase(),
+		codec.NewGPT2Base(),
+	}
+}
+
+func FuzzEncodeDecode(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"hello world",
+		"🙂 👍🏽 👨‍👩‍👧‍👦 🇯🇵 ❤️‍🔥",
+		"日本語のテキスト、中文分词，한국어 문장",
+		"مرحبا بالعالم שלום עולם",
+		"‏‎RTL marks‮ reversed‬",
+		strings.Repeat(" ", 100),
+		"\t\t\n\n  \r\n 　  x   \n\n\n   ",
+		"func main() {\n\tfmt.Println(\"hi\")\n}\n",
+		"caf\xe9 \xff\xfe \xf0\x9f",
+		"<|endoftext|><|eot_id|>",
+	} {
+		f.Add(seed)
+	}
+	codecs := roundTripCodecs()
+
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, c := range codecs {
+			ids, _, err := c.Encode(text)
+			if err != nil {
+				t.Fatalf("%s: Encode(%q) error: %v", c.GetName(), text, err)
+			}
+			decoded, err := c.Decode(ids)
+			if err != nil {
+				t.Fatalf("%s: Decode(%v) error: %v", c.GetName(), ids, err)
+			}
+			if utf8.ValidString(text) && decoded != text {
+				t.Fatalf("%s: Decode(Encode(%q)) = %q", c.GetName(), text, decoded)
+			}
+		}
+	})
+}
+
+func TestControlCharacters(t *testing.T) {
+	var c0 []byte
+	for b := byte(0); b < 0x20; b++ {
+		c0 = append(c0, b)
+	}
+	texts := []string{
+		string(c0) + "\x7f",
+		"log\x00line\x07bell \x1b[31mred\x1b[0m\x7f\r\n",
+	}
+	for _, b := range append(c0, 0x7f) {
+		texts = append(texts, string(b), "a"+string(b)+"b", " "+string(b)+" ", strings.Repeat(string(b), 5))
+	}
+
+	for _, c := range roundTripCodecs() {
+		for _, text := range texts {
+			if err := c.Verify(text); err != nil {
+				t.Errorf("%s: %v", c.GetName(), err)
+			}
+		}
+	}
+}
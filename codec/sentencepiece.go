@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// spaceMarker is the SentencePiece meta symbol (U+2581) that stands in
+	// for a space character inside vocabulary pieces.
+	spaceMarker = "▁"
+
+	// sentencePiecePatStr splits normalized SentencePiece text into runs of
+	// space markers followed by the next word. SentencePiece vocabularies
+	// never contain a marker after a non-marker character, so no merge can
+	// cross these boundaries.
+	sentencePiecePatStr = `▁*[^▁]+|▁+`
+)
+
+// sentencePiece holds the options that switch a Codec from byte-level BPE to
+// SentencePiece BPE conventions.
+type sentencePiece struct {
+	// addDummyPrefix prepends a space marker to the input, so the first word
+	// is tokenized the same way as a word in the middle of a sentence.
+	addDummyPrefix bool
+}
+
+// normalize replaces spaces with the space marker and applies the dummy
+// prefix if prefix is set.
+func (sp *sentencePiece) normalize(input string, prefix bool) string {
+	if input == "" {
+		return input
+	}
+	input = strings.ReplaceAll(input, " ", spaceMarker)
+	if sp.addDummyPrefix && prefix {
+		input = spaceMarker + input
+	}
+	return input
+}
+
+// decodePiece turns a vocabulary piece back into text, resolving byte
+// fallback pieces such as <0x0A> into their raw byte.
+func (sp *sentencePiece) decodePiece(piece string) string {
+	if b, ok := parseBytePiece(piece); ok {
+		return string([]byte{b})
+	}
+	return strings.ReplaceAll(piece, spaceMarker, " ")
+}
+
+// trimDummyPrefix removes the space added by normalize from the first
+// decoded piece.
+func (sp *sentencePiece) trimDummyPrefix(piece string) string {
+	if sp.addDummyPrefix {
+		return strings.TrimPrefix(piece, " ")
+	}
+	return piece
+}
+
+// encodeBytes yields the <0xXX> byte pieces for a token that is missing from
+// the vocabulary.
+func (c *Codec) encodeBytes(token string, yield func(uint, string)) error {
+	for i := 0; i < len(token); i++ {
+		piece := bytePiece(token[i])
+		id, ok := c.vocabulary[piece]
+		if !ok {
+			return fmt.Errorf("no byte fallback for %q in %s", token[i], c.name)
+		}
+		yield(id, piece)
+	}
+	return nil
+}
+
+func bytePiece(b byte) string {
+	return fmt.Sprintf("<0x%02X>", b)
+}
+
+func parseBytePiece(piece string) (byte, bool) {
+	if len(piece) != 6 || !strings.HasPrefix(piece, "<0x") || !strings.HasSuffix(piece, ">") {
+		return 0, false
+	}
+	b, err := strconv.ParseUint(piece[3:5], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return byte(b), true
+}
@@ -0,0 +1,22 @@
+package codec
+
+// NewSentencePieceBase returns the Llama 2 SentencePiece tokenizer with its
+// 32k vocabulary, which Code Llama, Vicuna and most Llama 2 fine-tunes share.
+// A dummy prefix is added, so a leading word is tokenized like a word in the
+// middle of a sentence.
+func NewSentencePieceBase(opts ...Option) *Codec {
+	sentencePieceVocabOnce.Do(sentencePieceVocabInit)
+
+	return withOptions(&Codec{
+		name:          "sentencepiece",
+		vocabulary:    currentVocab("sentencepiece", sentencePieceVocab),
+		pretokenizer:  mustRegexPretokenizer(sentencePiecePatStr),
+		sentencePiece: &sentencePiece{addDummyPrefix: true},
+		byteFallback:  true,
+		specialTokens: map[string]uint{
+			"<unk>": 0,
+			"<s>":   1,
+			"</s>":  2,
+		},
+	}, opts)
+}
@@ -0,0 +1,197 @@
+package codec
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestSentencePiece builds a tiny SentencePiece codec. Ranks are ids, so
+// lower ids merge first, matching the descending scores of real models.
+func newTestSentencePiece() *Codec {
+	return &Codec{
+		name: "sentencepiece_test",
+		vocabulary: vocab{
+			"<0xC3>": 3,
+			"<0xA9>": 4,
+			"<0x0A>": 5,
+			"▁":      10,
+			"h":      11,
+			"e":      12,
+			"l":      13,
+			"o":      14,
+			"w":      15,
+			"r":      16,
+			"d":      17,
+			"▁h":     20,
+			"ll":     21,
+			"▁he":    22,
+			"llo":    23,
+			"▁w":     25,
+			"or":     26,
+			"▁wor":   27,
+			"▁world": 28,
+			"ld":     29,
+		},
+		pretokenizer:  mustRegexPretokenizer(sentencePiecePatStr),
+		sentencePiece: &sentencePiece{addDummyPrefix: true},
+		byteFallback:  true,
+	}
+}
+
+func TestSentencePiece(t *testing.T) {
+	tok := newTestSentencePiece()
+
+	tests := []struct {
+		text   string
+		ids    []uint
+		tokens []string
+	}{
+		{text: "hello world", ids: []uint{22, 23, 28}, tokens: []string{"▁he", "llo", "▁world"}},
+		{text: "hello  world", ids: []uint{22, 23, 10, 28}, tokens: []string{"▁he", "llo", "▁", "▁world"}},
+		// é and the newline are not in the vocabulary and fall back to bytes
+		{text: "hé\n", ids: []uint{20, 3, 4, 5}, tokens: []string{"▁h", "<0xC3>", "<0xA9>", "<0x0A>"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			ids, tokens, err := tok.Encode(test.text)
+			if err != nil {
+				t.Fatalf("error encoding: %v", err)
+			}
+			if !slices.Equal(ids, test.ids) {
+				t.Errorf("encoding mismatch - want: %v got: %v", test.ids, ids)
+			}
+			if !slices.Equal(tokens, test.tokens) {
+				t.Errorf("tokens mismatch - want: %q got: %q", test.tokens, tokens)
+			}
+
+			text, err := tok.Decode(ids)
+			if err != nil {
+				t.Fatalf("error decoding: %v", err)
+			}
+			if text != test.text {
+				t.Errorf("decoding mismatch - want: %q got: %q", test.text, text)
+			}
+		})
+	}
+}
+
+func TestSentencePieceMissingByte(t *testing.T) {
+	tok := newTestSentencePiece()
+
+	if _, _, err := tok.Encode("x"); err == nil {
+		t.Fatal("expected an error for a character without byte fallback")
+	}
+}
+
+func TestSentencePieceBase(t *testing.T) {
+	tok := NewSentencePieceBase()
+
+	// reference ids from the Hugging Face Llama 2 tokenizer
+	tests := []struct {
+		text string
+		ids  []uint
+	}{
+		{text: "Hello world", ids: []uint{15043, 3186}},
+		{
+			text: "The fox jumped over the hare.\nThe turtle is faster than the hare.",
+			ids:  []uint{450, 1701, 29916, 12500, 287, 975, 278, 447, 276, 29889, 13, 1576, 260, 4227, 280, 338, 8473, 1135, 278, 447, 276, 29889},
+		},
+		// the emoji isn't in the vocabulary and falls back to its bytes
+		{text: "👍", ids: []uint{29871, 243, 162, 148, 144}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			ids, _, err := tok.Encode(test.text)
+			if err != nil {
+				t.Fatalf("error encoding: %v", err)
+			}
+			if !slices.Equal(ids, test.ids) {
+				t.Errorf("encoding mismatch - want: %v got: %v", test.ids, ids)
+			}
+
+			text, err := tok.Decode(ids)
+			if err != nil {
+				t.Fatalf("error decoding: %v", err)
+			}
+			if text != test.text {
+				t.Errorf("decoding mismatch - want: %q got: %q", test.text, text)
+			}
+		})
+	}
+}
+
+func TestEncodeContinuation(t *testing.T) {
+	tok := newTestSentencePiece()
+
+	// the dummy prefix makes a prompt start with a space
+	ids, _, err := tok.Encode("world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cont, _, err := tok.EncodeContinuation(" world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []uint{28}) || !slices.Equal(cont, ids) {
+		t.Errorf("Encode(\"world\") = %v, EncodeContinuation(\" world\") = %v, want [28]", ids, cont)
+	}
+
+	// a continuation without a space joins the previous word
+	cont, tokens, err := tok.EncodeContinuation("world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tokens, []string{"w", "or", "ld"}) {
+		t.Errorf("EncodeContinuation(\"world\") = %v %q, want w or ld", cont, tokens)
+	}
+}
+
+func TestEncodePartial(t *testing.T) {
+	tok := newTestSentencePiece()
+
+	// ü falls back to <0xC3> <0xBC>, and there is no <0xBC>
+	text := "hello ü world"
+	want := []uint{22, 23}
+
+	ids, tokens, err := tok.Encode(text)
+	if err == nil {
+		t.Fatalf("Encode(%q) = %v, want an error", text, ids)
+	}
+	if !slices.Equal(ids, want) || !slices.Equal(tokens, []string{"▁he", "llo"}) {
+		t.Errorf("Encode(%q) = %v %q, want the ids %v of the pieces before ü", text, ids, tokens, want)
+	}
+
+	for name, encode := range map[string]func(string) ([]uint, error){
+		"EncodeOrdinary": tok.EncodeOrdinary,
+		"EncodeWithLengths": func(s string) ([]uint, error) {
+			ids, _, err := tok.EncodeWithLengths(s)
+			return ids, err
+		},
+		"EncodeWithRuneOffsets": func(s string) ([]uint, error) {
+			ids, _, err := tok.EncodeWithRuneOffsets(s)
+			return ids, err
+		},
+		"EncodeDecodedPieces": func(s string) ([]uint, error) {
+			ids, _, err := tok.EncodeDecodedPieces(s)
+			return ids, err
+		},
+		"EncodeWithSpecialTokens": func(s string) ([]uint, error) {
+			ids, _, err := tok.EncodeWithSpecialTokens(s, nil)
+			return ids, err
+		},
+		"Encoder": func(s string) ([]uint, error) {
+			return tok.NewEncoder().Encode(s, nil)
+		},
+		"EncodeResult": func(s string) ([]uint, error) {
+			result, err := tok.EncodeResult(s)
+			return result.IDs, err
+		},
+	} {
+		ids, err := encode(text)
+		if err == nil || !slices.Equal(ids, want) {
+			t.Errorf("%s(%q) = %v, %v, want %v and an error", name, text, ids, err, want)
+		}
+	}
+}
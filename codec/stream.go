@@ -0,0 +1,67 @@
+package codec
+
+import "unicode/utf8"
+
+// StreamDecoder decodes tokens as they arrive, e.g. from a streaming LLM
+// response. Tokens can end in the middle of a multi-byte character, so the
+// decoder only returns complete UTF-8 characters and keeps the rest for the
+// next call.
+type StreamDecoder struct {
+	codec   *Codec
+	pending []byte
+	started bool
+}
+
+// NewStreamDecoder returns a StreamDecoder for tokens of the codec. Its
+// output matches what Decode returns for all the tokens at once.
+func NewStreamDecoder(c *Codec) *StreamDecoder {
+	return &StreamDecoder{codec: c}
+}
+
+// Write decodes the token IDs and returns the text that is complete so far.
+// On an unknown token ID it returns the text decoded before it and an error.
+func (d *StreamDecoder) Write(tokens []uint) (string, error) {
+	for _, t := range tokens {
+		piece, err := d.codec.decodeToken(t)
+		if err != nil {
+			return d.complete(), err
+		}
+		if !d.started && d.codec.sentencePiece != nil {
+			piece = d.codec.sentencePiece.trimDummyPrefix(piece)
+		}
+		if d.codec.wordPiece != nil {
+			piece = d.codec.wordPiece.decodePiece(piece, !d.started)
+		}
+		d.started = true
+		d.pending = append(d.pending, piece...)
+	}
+	return d.complete(), nil
+}
+
+// Flush returns whatever is left over, even if it isn't valid UTF-8, and
+// resets the decoder.
+func (d *StreamDecoder) Flush() string {
+	out := string(d.pending)
+	d.pending = d.pending[:0]
+	d.started = false
+	return out
+}
+
+// complete returns the pending bytes up to a trailing incomplete UTF-8
+// sequence, which is kept.
+func (d *StreamDecoder) complete() string {
+	n := len(d.pending)
+	for i := 1; i < utf8.UTFMax && i <= len(d.pending); i++ {
+		if !utf8.RuneStart(d.pending[len(d.pending)-i]) {
+			continue
+		}
+		if !utf8.FullRune(d.pending[len(d.pending)-i:]) {
+			n = len(d.pending) - i
+		}
+		break
+	}
+
+	out := string(d.pending[:n])
+	d.pending = append(d.pending[:0], d.pending[n:]...)
+	return out
+}
@@ -0,0 +1,95 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	input := "Streaming 🙂👋🏽 works, 日本語 too 🚀!"
+
+	for _, c := range []*codec.Codec{codec.NewCl100kBase(), codec.NewGemmaBase()} {
+		t.Run(c.GetName(), func(t *testing.T) {
+			ids, _, err := c.Encode(input)
+			if err != nil {
+				t.Fatalf("Encode() error: %v", err)
+			}
+
+			d := codec.NewStreamDecoder(c)
+			var out strings.Builder
+			for _, id := range ids {
+				chunk, err := d.Write([]uint{id})
+				if err != nil {
+					t.Fatalf("Write() error: %v", err)
+				}
+				if !utf8.ValidString(chunk) || strings.ContainsRune(chunk, utf8.RuneError) {
+					t.Fatalf("Write() returned a partial character: %q", chunk)
+				}
+				out.WriteString(chunk)
+			}
+			out.WriteString(d.Flush())
+
+			if out.String() != input {
+				t.Fatalf("decoded %q, want %q", out.String(), input)
+			}
+		})
+	}
+}
+
+func TestStreamDecoderWordPiece(t *testing.T) {
+	c := codec.NewBertBase()
+	ids, _, err := c.Encode("Embeddings are simple, streaming them too.")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	want, err := c.Decode(ids)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+
+	d := codec.NewStreamDecoder(c)
+	var out strings.Builder
+	for _, id := range ids {
+		chunk, err := d.Write([]uint{id})
+		if err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		out.WriteString(chunk)
+	}
+	out.WriteString(d.Flush())
+
+	if out.String() != want {
+		t.Fatalf("decoded %q, want %q like Decode", out.String(), want)
+	}
+}
+
+func TestStreamDecoderFlush(t *testing.T) {
+	c := codec.NewCl100kBase()
+
+	// "🙂" is split over several byte tokens
+	ids, _, err := c.Encode("🙂")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if len(ids) < 2 {
+		t.Skipf("🙂 is a single token")
+	}
+
+	d := codec.NewStreamDecoder(c)
+	chunk, err := d.Write(ids[:1])
+	if err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if chunk != "" {
+		t.Fatalf("Write() = %q, want the partial character held back", chunk)
+	}
+	if rest := d.Flush(); rest == "" {
+		t.Fatalf("Flush() returned nothing")
+	}
+	if rest := d.Flush(); rest != "" {
+		t.Fatalf("second Flush() = %q, want empty", rest)
+	}
+}
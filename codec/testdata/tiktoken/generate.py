@@ -0,0 +1,20 @@
+"""Writes the <encoding>.json fixtures from samples.json with tiktoken.
+
+    pip install tiktoken
+    python generate.py
+"""
+import json
+
+import tiktoken
+
+ENCODINGS = ["r50k_base", "p50k_base", "cl100k_base", "o200k_base"]
+
+with open("samples.json", encoding="utf-8") as f:
+    samples = json.load(f)
+
+for name in ENCODINGS:
+    enc = tiktoken.get_encoding(name)
+    fixture = [{"text": s, "ids": enc.encode_ordinary(s)} for s in samples]
+    with open(name + ".json", "w", encoding="utf-8") as f:
+        json.dump(fixture, f, ensure_ascii=False, indent=1)
+        f.write("\n")
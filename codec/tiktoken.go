@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LoadTiktoken builds a codec from a .tiktoken vocabulary read at runtime.
+// Each line holds a base64 encoded token and its rank separated by a space,
+// an optional #version header on the first line is skipped.
+func LoadTiktoken(r io.Reader, pattern string, specialTokens map[string]uint) (*Codec, error) {
+	pretokenizer, err := NewRegexPretokenizer(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	vocabulary, err := parseTiktoken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Codec{
+		name:          "tiktoken",
+		vocabulary:    vocabulary,
+		pretokenizer:  pretokenizer,
+		specialTokens: make(map[string]uint, len(specialTokens)),
+	}
+	for name, id := range specialTokens {
+		if err := c.AddSpecialToken(name, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func parseTiktoken(r io.Reader) (vocab, error) {
+	vocabulary := make(vocab)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if lineNum == 1 && strings.Contains(line, "#version") {
+			continue // skip the version line
+		}
+		if line == "" {
+			continue
+		}
+
+		wordInput, idInput, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid line: %q", lineNum, line)
+		}
+
+		word, err := base64.StdEncoding.DecodeString(wordInput)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid word: %q", lineNum, wordInput)
+		}
+
+		id, err := strconv.ParseUint(idInput, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid id: %q", lineNum, idInput)
+		}
+
+		vocabulary[string(word)] = uint(id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading vocabulary: %v", err)
+	}
+
+	return vocabulary, nil
+}
@@ -0,0 +1,75 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+type fixtureEntry struct {
+	Text string `json:"text"`
+	IDs  []uint `json:"ids"`
+}
+
+// TestTiktokenFixtures compares every OpenAI encoding against the ids the
+// tiktoken library produces for testdata/tiktoken/samples.json. Fixtures are
+// written by testdata/tiktoken/generate.py; an encoding without one is
+// skipped, but a fixture that doesn't cover every sample fails.
+func TestTiktokenFixtures(t *testing.T) {
+	var samples []string
+	readJSON(t, "testdata/tiktoken/samples.json", &samples)
+
+	for _, c := range []*codec.Codec{
+		codec.NewR50kBase(),
+		codec.NewP50kBase(),
+		codec.NewCl100kBase(),
+		codec.NewO200kBase(),
+	} {
+		t.Run(c.GetName(), func(t *testing.T) {
+			path := filepath.Join("testdata", "tiktoken", c.GetName()+".json")
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				t.Skipf("no fixture at %s, run generate.py to create it", path)
+			}
+			var fixture []fixtureEntry
+			readJSON(t, path, &fixture)
+
+			covered := make(map[string]bool, len(fixture))
+			for _, entry := range fixture {
+				covered[entry.Text] = true
+
+				ids, err := c.EncodeOrdinary(entry.Text)
+				if err != nil {
+					t.Fatalf("EncodeOrdinary(%q) error: %v", entry.Text, err)
+				}
+				if !slices.Equal(ids, entry.IDs) {
+					t.Errorf("EncodeOrdinary(%q) = %v, want %v", entry.Text, ids, entry.IDs)
+				}
+				if text, err := c.Decode(entry.IDs); err != nil || text != entry.Text {
+					t.Errorf("Decode(%v) = %q, %v, want %q", entry.IDs, text, err, entry.Text)
+				}
+			}
+			for _, sample := range samples {
+				if !covered[sample] {
+					t.Errorf("%s has no entry for %q, regenerate it", path, sample)
+				}
+			}
+		})
+	}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
@@ -0,0 +1,130 @@
+package codec_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+const testPattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// testTiktoken renders words as a .tiktoken file, using the index as rank.
+func testTiktoken(words ...string) string {
+	var b strings.Builder
+	for i, word := range words {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(word)), i)
+	}
+	return b.String()
+}
+
+func TestLoadTiktoken(t *testing.T) {
+	vocab := "#version: 2\n" + testTiktoken("a", "b", "c", " ", "ab", " a", " ab", "abc")
+
+	tok, err := codec.LoadTiktoken(strings.NewReader(vocab), testPattern, map[string]uint{"<|endoftext|>": 8})
+	if err != nil {
+		t.Fatalf("can't load tokenizer: %v", err)
+	}
+
+	ids, tokens, err := tok.Encode("abc abc ab")
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	if want := []uint{7, 6, 2, 6}; !slices.Equal(ids, want) {
+		t.Errorf("encoding mismatch - want: %v got: %v", want, ids)
+	}
+	if want := []string{"abc", " ab", "c", " ab"}; !slices.Equal(tokens, want) {
+		t.Errorf("tokens mismatch - want: %q got: %q", want, tokens)
+	}
+
+	text, err := tok.Decode(ids)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if text != "abc abc ab" {
+		t.Errorf("decoding mismatch - want: %q got: %q", "abc abc ab", text)
+	}
+}
+
+func TestLoadTiktokenErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		vocab   string
+		pattern string
+		err     string
+	}{
+		{name: "missing id", vocab: testTiktoken("a") + "Yg==\n", pattern: testPattern, err: "line 2: invalid line"},
+		{name: "invalid word", vocab: "!!! 0\n", pattern: testPattern, err: "line 1: invalid word"},
+		{name: "invalid id", vocab: testTiktoken("a") + "Yg== x\n", pattern: testPattern, err: "line 2: invalid id"},
+		{name: "invalid pattern", vocab: testTiktoken("a"), pattern: `(`, err: "invalid pattern"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := codec.LoadTiktoken(strings.NewReader(test.vocab), test.pattern, nil)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("want error containing %q, got: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestReloadVocab(t *testing.T) {
+	t.Cleanup(func() { codec.ResetVocab("r50k_base") })
+
+	vocab := testTiktoken("h", "e", "l", "o", " ", "w", "r", "d", "hello", " world")
+	encode := func() []uint {
+		ids, _, err := codec.NewR50kBase().Encode("hello world")
+		if err != nil {
+			t.Errorf("error encoding: %v", err)
+		}
+		return ids
+	}
+	embedded, replaced := []uint{31373, 995}, []uint{8, 9}
+
+	old := codec.NewR50kBase()
+	if err := codec.ReloadVocab("r50k_base", strings.NewReader(vocab)); err != nil {
+		t.Fatalf("ReloadVocab() error: %v", err)
+	}
+	if ids := encode(); !slices.Equal(ids, replaced) {
+		t.Errorf("encoding after reload - want: %v got: %v", replaced, ids)
+	}
+	// codecs created before the reload keep their vocabulary
+	if ids, _, _ := old.Encode("hello world"); !slices.Equal(ids, embedded) {
+		t.Errorf("existing codec changed - want: %v got: %v", embedded, ids)
+	}
+
+	// readers see one vocabulary or the other, never a mix
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 200 {
+			if ids := encode(); !slices.Equal(ids, embedded) && !slices.Equal(ids, replaced) {
+				t.Errorf("torn encoding: %v", ids)
+				return
+			}
+		}
+	}()
+	for range 200 {
+		codec.ResetVocab("r50k_base")
+		if err := codec.ReloadVocab("r50k_base", strings.NewReader(vocab)); err != nil {
+			t.Fatalf("ReloadVocab() error: %v", err)
+		}
+	}
+	<-done
+
+	codec.ResetVocab("r50k_base")
+	if ids := encode(); !slices.Equal(ids, embedded) {
+		t.Errorf("encoding after reset - want: %v got: %v", embedded, ids)
+	}
+
+	if err := codec.ReloadVocab("nope", strings.NewReader(vocab)); err == nil {
+		t.Error("ReloadVocab() accepted an unknown codec")
+	}
+	if err := codec.ReloadVocab("r50k_base", strings.NewReader("!!! 0\n")); err == nil {
+		t.Error("ReloadVocab() accepted an invalid vocabulary")
+	}
+}
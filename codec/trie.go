@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"slices"
+	"sort"
+)
+
+// trie is a byte trie over the vocabulary, flattened into slices so that a
+// 200k entry vocabulary doesn't turn into millions of small allocations. The
+// edges of a node are stored next to each other, sorted by label.
+type trie struct {
+	nodes   []trieNode
+	labels  []byte
+	targets []int32
+}
+
+type trieNode struct {
+	firstEdge int32
+	numEdges  uint16
+	// rank is -1 for nodes that don't end a token
+	rank int64
+}
+
+func newTrie(ranks vocab) *trie {
+	keys := make([]string, 0, len(ranks))
+	for k := range ranks {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	t := &trie{}
+	t.build(ranks, keys, 0)
+	return t
+}
+
+// build adds the node for the common prefix of keys, which are sorted and all
+// share their first depth bytes, and returns its index.
+func (t *trie) build(ranks vocab, keys []string, depth int) int32 {
+	index := int32(len(t.nodes))
+	t.nodes = append(t.nodes, trieNode{rank: -1})
+
+	if len(keys) > 0 && len(keys[0]) == depth {
+		t.nodes[index].rank = int64(ranks[keys[0]])
+		keys = keys[1:]
+	}
+
+	// keys are sorted, so the children are runs of the same byte at depth
+	var groups [][]string
+	for start := 0; start < len(keys); {
+		end := start + 1
+		for end < len(keys) && keys[end][depth] == keys[start][depth] {
+			end++
+		}
+		groups = append(groups, keys[start:end])
+		start = end
+	}
+
+	first := int32(len(t.labels))
+	t.nodes[index].firstEdge = first
+	t.nodes[index].numEdges = uint16(len(groups))
+	for _, group := range groups {
+		t.labels = append(t.labels, group[0][depth])
+		t.targets = append(t.targets, 0)
+	}
+	for i, group := range groups {
+		t.targets[first+int32(i)] = t.build(ranks, group, depth+1)
+	}
+
+	return index
+}
+
+func (t *trie) rank(token string) (uint, bool) {
+	node := int32(0)
+	for i := 0; i < len(token); i++ {
+		n := t.nodes[node]
+		labels := t.labels[n.firstEdge : n.firstEdge+int32(n.numEdges)]
+		j := sort.Search(len(labels), func(j int) bool { return labels[j] >= token[i] })
+		if j == len(labels) || labels[j] != token[i] {
+			return 0, false
+		}
+		node = t.targets[n.firstEdge+int32(j)]
+	}
+	if rank := t.nodes[node].rank; rank >= 0 {
+		return uint(rank), true
+	}
+	return 0, false
+}
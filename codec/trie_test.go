@@ -0,0 +1,67 @@
+package codec_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestTrie(t *testing.T) {
+	tests := []struct {
+		name    string
+		newFunc func(...codec.Option) *codec.Codec
+	}{
+		{"o200k_base", codec.NewO200kBase},
+		{"llama", codec.NewLLama3Base},
+		{"gemma", codec.NewGemmaBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMap := tt.newFunc()
+			withTrie := tt.newFunc(codec.WithTrie())
+
+			for _, text := range countTexts {
+				want, _, err := withMap.Encode(text)
+				if err != nil {
+					t.Fatalf("Encode(%q) error: %v", text, err)
+				}
+				got, _, err := withTrie.Encode(text)
+				if err != nil {
+					t.Fatalf("Encode(%q) with trie error: %v", text, err)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("Encode(%q) with trie = %v, want %v", text, got, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTrie(b *testing.B) {
+	text := strings.Repeat(strings.Join(countTexts, " "), 20)
+
+	for _, bb := range []struct {
+		name  string
+		codec *codec.Codec
+	}{
+		{"map", codec.NewO200kBase()},
+		{"trie", codec.NewO200kBase(codec.WithTrie())},
+	} {
+		// build the trie outside of the timed loop
+		if _, _, err := bb.codec.Encode(text); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				if _, _, err := bb.codec.Encode(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
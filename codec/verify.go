@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// verifyContext is how many bytes around a mismatch Verify quotes.
+const verifyContext = 16
+
+// Verify encodes the text, decodes the ids again and returns an error
+// describing the first difference if the result isn't byte for byte the
+// input. Special tokens in the text are encoded as ordinary text. It is meant
+// for checking new vocabularies, not for every call.
+func (c *Codec) Verify(text string) error {
+	ids, lengths, err := c.EncodeWithLengths(text)
+	if err != nil {
+		return fmt.Errorf("error encoding: %v", err)
+	}
+	decoded, err := c.DecodeToBytes(ids)
+	if err != nil {
+		return fmt.Errorf("error decoding: %v", err)
+	}
+	if string(decoded) == text {
+		return nil
+	}
+
+	input := []byte(text)
+	at := 0
+	for at < len(input) && at < len(decoded) && input[at] == decoded[at] {
+		at++
+	}
+
+	token, end := 0, 0
+	for token < len(lengths) {
+		end += lengths[token]
+		if end > at {
+			break
+		}
+		token++
+	}
+	if token == len(ids) {
+		return fmt.Errorf("%s: round trip differs at byte %d: input %q, decoded %q",
+			c.name, at, snippet(input, at), snippet(decoded, at))
+	}
+	return fmt.Errorf("%s: round trip differs at byte %d in token %d (id %d): input %q, decoded %q",
+		c.name, at, token, ids[token], snippet(input, at), snippet(decoded, at))
+}
+
+// snippet returns the bytes around offset at.
+func snippet(b []byte, at int) []byte {
+	start := max(at-verifyContext, 0)
+	end := min(at+verifyContext, len(b))
+	if start > end {
+		return nil
+	}
+	return bytes.Clone(b[start:end])
+}
@@ -0,0 +1,41 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestVerify(t *testing.T) {
+	texts := []string{
+		"The quick brown fox jumps over the lazy dog.",
+		"Größenwahn, naïve café, 東京タワー, Москва, مرحبا, 👋🏽",
+		"func main() {\n\treturn 42\n}\n",
+		"<|endoftext|> is ordinary text here",
+	}
+	for _, c := range []*codec.Codec{codec.NewCl100kBase(), codec.NewO200kBase(), codec.NewGemmaBase()} {
+		for _, text := range texts {
+			if err := c.Verify(text); err != nil {
+				t.Errorf("%s: Verify(%q) error: %v", c.GetName(), text, err)
+			}
+		}
+	}
+}
+
+func TestVerifyBroken(t *testing.T) {
+	// "a" and "b" share an id, so one of them decodes as the other
+	c := codec.New(codec.Config{
+		Name:       "broken",
+		Vocabulary: map[string]uint{"a": 0, "b": 0, " ": 1},
+		Pattern:    testPattern,
+	})
+
+	err := c.Verify("ab ab")
+	if err == nil {
+		t.Fatal("Verify() passed for a broken codec")
+	}
+	if !strings.Contains(err.Error(), "round trip differs at byte") || !strings.Contains(err.Error(), "id 0") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
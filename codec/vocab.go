@@ -1,4 +1,88 @@
 package codec
 
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
 type vocab map[string]uint
 type reverse map[uint]string
+
+// mustParseVocab parses a vocabulary embedded in the .tiktoken format. The
+// data is generated, so an error is a bug.
+func mustParseVocab(data string) vocab {
+	v, err := parseTiktoken(strings.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded vocabulary: %v", err))
+	}
+	return v
+}
+
+// mustParseCompressedVocab is mustParseVocab for gzip compressed data, which
+// the tokenizer_gzip build tag embeds instead to make binaries smaller.
+func mustParseCompressedVocab(data string) vocab {
+	zr, err := gzip.NewReader(strings.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded vocabulary: %v", err))
+	}
+	v, err := parseTiktoken(zr)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded vocabulary: %v", err))
+	}
+	return v
+}
+
+// builtinCodecs are the names of the codecs with an embedded vocabulary.
+var builtinCodecs = map[string]bool{
+	"r50k_base": true, "p50k_base": true, "p50k_edit": true, "cl100k_base": true,
+	"o200k_base": true, "llama": true, "anthropic": true, "gemma": true, "gpt2": true,
+	"bert": true, "sentencepiece": true, "mistral": true,
+}
+
+// reloaded holds the vocabularies swapped in with ReloadVocab by codec name.
+var reloaded = struct {
+	sync.RWMutex
+	vocabs map[string]vocab
+}{vocabs: make(map[string]vocab)}
+
+// ReloadVocab parses a vocabulary in the .tiktoken format and makes the
+// built-in codec with the given name, e.g. "cl100k_base", use it instead of
+// the embedded one. Codecs created before keep the vocabulary they were
+// created with, so encodings in flight are never affected; codecs created
+// afterwards use the new one.
+func ReloadVocab(name string, r io.Reader) error {
+	if !builtinCodecs[name] {
+		return fmt.Errorf("unknown codec: %q", name)
+	}
+	v, err := parseTiktoken(r)
+	if err != nil {
+		return err
+	}
+
+	reloaded.Lock()
+	defer reloaded.Unlock()
+	reloaded.vocabs[name] = v
+	return nil
+}
+
+// ResetVocab makes the built-in codec with the given name use its embedded
+// vocabulary again.
+func ResetVocab(name string) {
+	reloaded.Lock()
+	defer reloaded.Unlock()
+	delete(reloaded.vocabs, name)
+}
+
+// currentVocab returns the reloaded vocabulary of the codec, or the embedded
+// one if there is none.
+func currentVocab(name string, embedded vocab) vocab {
+	reloaded.RLock()
+	defer reloaded.RUnlock()
+	if v, ok := reloaded.vocabs[name]; ok {
+		return v
+	}
+	return embedded
+}
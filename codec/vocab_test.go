@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestEmbeddedVocab checks the embedded r50k_base data against the map
+// literal it replaced, hashed as sorted "base64 id" lines.
+func TestEmbeddedVocab(t *testing.T) {
+	r50kBaseVocabOnce.Do(r50kBaseVocabInit)
+
+	if len(r50kBaseVocab) != 50256 {
+		t.Fatalf("len(r50kBaseVocab) = %d, want 50256", len(r50kBaseVocab))
+	}
+
+	words := make([]string, 0, len(r50kBaseVocab))
+	for word := range r50kBaseVocab {
+		words = append(words, word)
+	}
+	slices.SortFunc(words, func(a, b string) int {
+		return int(r50kBaseVocab[a]) - int(r50kBaseVocab[b])
+	})
+
+	h := sha256.New()
+	for _, word := range words {
+		fmt.Fprintf(h, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(word)), r50kBaseVocab[word])
+	}
+
+	const want = "306cd27f03c1a714eca7108e03d66b7dc042abe8c258b44c199a7ed9838dd930"
+	if got := fmt.Sprintf("%x", h.Sum(nil)); got != want {
+		t.Fatalf("r50k_base vocabulary hash = %s, want %s", got, want)
+	}
+}
+
+// TestCompressedVocab checks that the gzip compressed data embedded with the
+// tokenizer_gzip build tag holds the same vocabularies as the plain data.
+func TestCompressedVocab(t *testing.T) {
+	files, err := filepath.Glob("data/*.tiktoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		plain, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed, err := os.ReadFile(file + ".gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(mustParseCompressedVocab(string(compressed)), mustParseVocab(string(plain))) {
+			t.Errorf("%s.gz holds a different vocabulary", file)
+		}
+	}
+
+	compressed, err := os.ReadFile("data/o200k_base.tiktoken.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewO200kBase()
+	c := New(Config{
+		Name:          "o200k_base",
+		Vocabulary:    mustParseCompressedVocab(string(compressed)),
+		Pattern:       o200kPatStr,
+		SpecialTokens: want.SpecialTokens(),
+	})
+	for _, text := range []string{"hello world", "Hello, 世界! 🙂\n\tcafé", "func main() {}\n"} {
+		wantIDs, _, err := want.Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, _, err := c.Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ids, wantIDs) {
+			t.Errorf("Encode(%q) = %v with the compressed vocabulary, want %v", text, ids, wantIDs)
+		}
+	}
+}
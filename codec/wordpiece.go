@@ -0,0 +1,130 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// continuationPrefix marks WordPiece tokens that continue a word rather
+	// than start one.
+	continuationPrefix = "##"
+
+	// wordPieceMaxChars is the longest word WordPiece tries to split, longer
+	// words are unknown.
+	wordPieceMaxChars = 100
+
+	// bertPunct is what BERT splits off as punctuation: every ASCII symbol
+	// and \p{P}.
+	bertPunct = `!-/:-@\[-` + "`" + `{-~\p{P}`
+	// bertCJK are the CJK ideographs BERT splits into single characters.
+	bertCJK = "\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\U00020000-\U0002a6df" +
+		"\U0002a700-\U0002b73f\U0002b740-\U0002b81f\U0002b820-\U0002ceaf\U0002f800-\U0002fa1f"
+
+	// bertPatStr splits text into words, single punctuation characters and
+	// single CJK ideographs, like BERT's basic tokenizer.
+	bertPatStr = `[^\s` + bertPunct + bertCJK + `]+|[` + bertPunct + bertCJK + `]`
+)
+
+// wordPiece holds the options that switch a Codec from BPE to WordPiece, the
+// greedy longest-match-first encoding used by BERT.
+type wordPiece struct {
+	// unknown is the token for words that can't be covered by the vocabulary.
+	unknown string
+	// lowercase lowercases the input, for uncased vocabularies.
+	lowercase bool
+}
+
+// NewWordPiece returns a WordPiece codec for the vocabulary in cfg. Words
+// are split into the longest vocabulary token that starts them followed by
+// the longest ## continuation tokens, and become [UNK] when that fails. An
+// empty cfg.Pattern splits words like BERT. Decoding joins words with a
+// space, so the original spacing around punctuation isn't restored.
+func NewWordPiece(cfg Config, opts ...Option) *Codec {
+	pattern := cfg.Pattern
+	if pattern == "" {
+		pattern = bertPatStr
+	}
+
+	return withOptions(&Codec{
+		name:          cfg.Name,
+		vocabulary:    cfg.Vocabulary,
+		pretokenizer:  mustRegexPretokenizer(pattern),
+		specialTokens: cfg.SpecialTokens,
+		wordPiece:     &wordPiece{unknown: "[UNK]"},
+	}, opts)
+}
+
+// normalize drops the control characters BERT removes before splitting and
+// lowercases the input if needed.
+func (wp *wordPiece) normalize(input string) string {
+	if wp.lowercase {
+		input = strings.ToLower(input)
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r == 0, r == utf8.RuneError, unicode.In(r, unicode.Cc, unicode.Cf):
+			return -1
+		}
+		return r
+	}, input)
+}
+
+// encodeWord yields the WordPiece tokens of a single word.
+func (c *Codec) encodeWord(word string, yield func(uint, string)) error {
+	if utf8.RuneCountInString(word) <= wordPieceMaxChars {
+		var ids []uint
+		var tokens []string
+		for start := 0; start < len(word); {
+			end := len(word)
+			for end > start {
+				token := word[start:end]
+				if start > 0 {
+					token = continuationPrefix + token
+				}
+				if id, ok := c.vocabulary[token]; ok {
+					ids = append(ids, id)
+					tokens = append(tokens, token)
+					break
+				}
+				_, size := utf8.DecodeLastRuneInString(word[start:end])
+				end -= size
+			}
+			if end == start {
+				// a word with any unknown part is unknown as a whole
+				ids = nil
+				break
+			}
+			start = end
+		}
+		if ids != nil {
+			for i, id := range ids {
+				yield(id, tokens[i])
+			}
+			return nil
+		}
+	}
+
+	id, ok := c.vocabulary[c.wordPiece.unknown]
+	if !ok {
+		return fmt.Errorf("no token for %q in %s", word, c.name)
+	}
+	yield(id, c.wordPiece.unknown)
+	return nil
+}
+
+// decodePiece turns a token back into text, joining continuation tokens to
+// the token before and separating words with a space.
+func (wp *wordPiece) decodePiece(piece string, first bool) string {
+	if rest, ok := strings.CutPrefix(piece, continuationPrefix); ok {
+		return rest
+	}
+	if first {
+		return piece
+	}
+	return " " + piece
+}
@@ -0,0 +1,85 @@
+package codec_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer/codec"
+)
+
+func TestBertBase(t *testing.T) {
+	c := codec.NewBertBase()
+
+	for _, tt := range []struct {
+		input  string
+		tokens []string
+		ids    []uint
+	}{
+		{"Hello, world!", []string{"hello", ",", "world", "!"}, []uint{7592, 1010, 2088, 999}},
+		{"Using a Transformer network is simple", []string{"using", "a", "transform", "##er", "network", "is", "simple"}, []uint{2478, 1037, 10938, 2121, 2897, 2003, 3722}},
+		{"tokenization", []string{"token", "##ization"}, []uint{19204, 3989}},
+		{"embeddings", []string{"em", "##bed", "##ding", "##s"}, []uint{7861, 8270, 4667, 2015}},
+		{"don't", []string{"don", "'", "t"}, []uint{2123, 1005, 1056}},
+		{"中文", []string{"中", "文"}, []uint{1746, 1861}},
+		{"ĀāĂ", []string{"[UNK]"}, []uint{100}},
+		{"", []string{}, []uint{}},
+	} {
+		ids, tokens, err := c.Encode(tt.input)
+		if err != nil {
+			t.Fatalf("Encode(%q) error: %v", tt.input, err)
+		}
+		if !slices.Equal(tokens, tt.tokens) || !slices.Equal(ids, tt.ids) {
+			t.Errorf("Encode(%q) = %v %q, want %v %q", tt.input, ids, tokens, tt.ids, tt.tokens)
+		}
+
+		count, err := c.Count(tt.input)
+		if err != nil {
+			t.Fatalf("Count(%q) error: %v", tt.input, err)
+		}
+		if count != len(tt.ids) {
+			t.Errorf("Count(%q) = %d, want %d", tt.input, count, len(tt.ids))
+		}
+	}
+}
+
+func TestBertBaseDecode(t *testing.T) {
+	c := codec.NewBertBase()
+
+	ids, _, err := c.EncodeWithSpecialTokens("[CLS] Embeddings are simple. [SEP]", map[string]bool{codec.AllowAllSpecial: true})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if ids[0] != 101 || ids[len(ids)-1] != 102 {
+		t.Errorf("ids = %v, want [CLS] ... [SEP]", ids)
+	}
+
+	text, err := c.Decode(ids)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if want := "[CLS] embeddings are simple . [SEP]"; text != want {
+		t.Errorf("Decode() = %q, want %q", text, want)
+	}
+}
+
+func TestWordPiece(t *testing.T) {
+	c := codec.NewWordPiece(codec.Config{
+		Name:       "tiny",
+		Vocabulary: map[string]uint{"[UNK]": 0, "un": 1, "##aff": 2, "##able": 3, "runn": 4, "##ing": 5, ",": 6},
+	})
+
+	ids, tokens, err := c.Encode("unaffable running, unknown")
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	wantTokens := []string{"un", "##aff", "##able", "runn", "##ing", ",", "[UNK]"}
+	if !slices.Equal(tokens, wantTokens) || !slices.Equal(ids, []uint{1, 2, 3, 4, 5, 6, 0}) {
+		t.Errorf("Encode() = %v %q, want %q", ids, tokens, wantTokens)
+	}
+
+	// without an unknown token the word can't be encoded
+	c = codec.NewWordPiece(codec.Config{Name: "tiny", Vocabulary: map[string]uint{"a": 0}})
+	if _, _, err := c.Encode("b"); err == nil {
+		t.Errorf("Encode() without [UNK] succeeded")
+	}
+}
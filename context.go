@@ -0,0 +1,171 @@
+package tokenizer
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrContextWindowExceeded is returned by AssembleMessages when not even the
+// system prompt fits the context window.
+var ErrContextWindowExceeded = errors.New("context window exceeded")
+
+// ContextWindows are the context windows of models in tokens, by model name
+// prefix. The longest matching prefix wins.
+var ContextWindows = map[string]int{
+	"gpt-3.5-turbo":          16385,
+	"gpt-3.5-turbo-instruct": 4096,
+	"gpt-4":                  8192,
+	"gpt-4-32k":              32768,
+	"gpt-4-turbo":            128000,
+	"gpt-4-1106":             128000,
+	"gpt-4-0125":             128000,
+	"gpt-4o":                 128000,
+	"gpt-4.1":                1047576,
+	"gpt-5":                  400000,
+	"o1":                     200000,
+	"o1-mini":                128000,
+	"o1-preview":             128000,
+	"o3":                     200000,
+	"o4-mini":                200000,
+	"claude-":                200000,
+	"claude-2.0":             100000,
+	"claude-instant":         100000,
+	"llama3":                 8192,
+	"llama3.1":               131072,
+	"llama3.2":               131072,
+	"llama3.3":               131072,
+}
+
+// ModelContextWindow returns the context window of the model in tokens, if
+// it is in ContextWindows. The model is normalized like NormalizeModel does.
+func ModelContextWindow(model Model) (int, bool) {
+	name, _, err := NormalizeModel(string(model))
+	if err != nil {
+		name = normalizeModel(model)
+	}
+	match := ""
+	for prefix := range ContextWindows {
+		if strings.HasPrefix(string(name), prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return 0, false
+	}
+	return ContextWindows[match], true
+}
+
+// AssembleMessages fits a chat history into the context window of the model,
+// leaving reserveForOutput tokens for the reply. A leading system message is
+// always kept, then as many of the most recent messages as fit. If room is
+// left, the newest message that didn't fit is kept too with its content cut
+// to fit, keeping its beginning. It returns the messages and their count
+// from CountMessages, or ErrContextWindowExceeded if not even the system
+// message fits.
+func AssembleMessages(model Model, messages []ChatMessage, reserveForOutput int) ([]ChatMessage, int, error) {
+	window, ok := ModelContextWindow(model)
+	if !ok {
+		return nil, 0, &UnsupportedModelError{Model: model}
+	}
+	budget := window - reserveForOutput
+
+	var pinned []ChatMessage
+	history := messages
+	if len(messages) > 0 && messages[0].Role == "system" {
+		pinned, history = messages[:1], messages[1:]
+	}
+	count := func(rest []ChatMessage) (int, error) {
+		return CountMessages(model, append(append([]ChatMessage{}, pinned...), rest...))
+	}
+
+	// fewer messages never count more, find the first that can be kept
+	lo, hi := 0, len(history)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		n, err := count(history[mid:])
+		if err != nil {
+			return nil, 0, err
+		}
+		if n <= budget {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	kept := history[lo:]
+	total, err := count(kept)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total > budget {
+		return nil, 0, ErrContextWindowExceeded
+	}
+
+	if lo > 0 {
+		truncated, n, err := truncateMessage(model, history[lo-1], func(m ChatMessage) (int, error) {
+			return count(append([]ChatMessage{m}, kept...))
+		}, budget)
+		if err != nil {
+			return nil, 0, err
+		}
+		if truncated.Content != "" {
+			kept = append([]ChatMessage{truncated}, kept...)
+			total = n
+		}
+	}
+
+	return append(append([]ChatMessage{}, pinned...), kept...), total, nil
+}
+
+// truncateMessage cuts the content of the message to the longest beginning
+// for which count stays within budget, on token boundaries. The content is
+// empty if no beginning fits.
+func truncateMessage(model Model, m ChatMessage, count func(ChatMessage) (int, error), budget int) (ChatMessage, int, error) {
+	enc, err := ForModel(model)
+	if err != nil {
+		return ChatMessage{}, 0, err
+	}
+	ids, _, err := enc.Encode(m.Content)
+	if err != nil {
+		return ChatMessage{}, 0, err
+	}
+
+	prefix := func(n int) (ChatMessage, error) {
+		text, err := enc.DecodeToBytes(ids[:n])
+		if err != nil {
+			return ChatMessage{}, err
+		}
+		// drop a rune cut in half at the end
+		for i := 1; i <= utf8.UTFMax && i <= len(text); i++ {
+			if tail := text[len(text)-i:]; utf8.RuneStart(tail[0]) {
+				if !utf8.FullRune(tail) {
+					text = text[:len(text)-i]
+				}
+				break
+			}
+		}
+		return ChatMessage{Role: m.Role, Content: string(text)}, nil
+	}
+
+	best, bestCount := ChatMessage{Role: m.Role}, 0
+	lo, hi := 1, len(ids)
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		candidate, err := prefix(mid)
+		if err != nil {
+			return ChatMessage{}, 0, err
+		}
+		n, err := count(candidate)
+		if err != nil {
+			return ChatMessage{}, 0, err
+		}
+		if n <= budget {
+			best, bestCount = candidate, n
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best, bestCount, nil
+}
@@ -0,0 +1,132 @@
+package tokenizer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/awee-ai/go-tokenizer"
+)
+
+var history = []tokenizer.ChatMessage{
+	{Role: "system", Content: "You are a helpful assistant."},
+	{Role: "user", Content: strings.Repeat("Tell me about the history of Rome. ", 20)},
+	{Role: "assistant", Content: strings.Repeat("Rome was founded, according to legend, in 753 BC. ", 20)},
+	{Role: "user", Content: "And what about Carthage?"},
+	{Role: "assistant", Content: "Carthage was a Phoenician city in North Africa."},
+	{Role: "user", Content: "Who won the Punic Wars?"},
+}
+
+func TestCountMessages(t *testing.T) {
+	enc, err := tokenizer.ForModel(tokenizer.GPT4o)
+	assert.NoError(t, err)
+	want := 3
+	for _, m := range history {
+		role, _ := enc.Count(m.Role)
+		content, _ := enc.Count(m.Content)
+		want += 3 + role + content
+	}
+	count, err := tokenizer.CountMessages(tokenizer.GPT4o, history)
+	assert.NoError(t, err)
+	assert.Equal(t, want, count)
+
+	count, err = tokenizer.CountMessages("claude-sonnet-4-20250514", history)
+	assert.NoError(t, err)
+	want, err = tokenizer.CountAnthropicMessages("claude-sonnet-4-20250514", history[0].Content, history[1:])
+	assert.NoError(t, err)
+	assert.Equal(t, want, count)
+
+	_, err = tokenizer.CountMessages("brand-new-model", history)
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
+
+// TestFlatVersusMessageCounts shows what prefixing the role to the content
+// and counting the flat string misses: the framing around the message.
+func TestFlatVersusMessageCounts(t *testing.T) {
+	content := "This tool uses Anthropic's newly released token counting api to count the number of tokens in a given text. Beware of existing tokenizers which are not accurate. Explore the source code here."
+	messages := []tokenizer.ChatMessage{{Role: "user", Content: content}}
+
+	for _, tt := range []struct {
+		model                  tokenizer.Model
+		content, flat, message int
+	}{
+		// the count_tokens API returned 49 for this request
+		{model: "claude-sonnet-4-20250514", content: 47, flat: 50, message: 49},
+		{model: tokenizer.GPT4o, content: 38, flat: 40, message: 45},
+	} {
+		t.Run(string(tt.model), func(t *testing.T) {
+			count, err := tokenizer.Count(tt.model, content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, count)
+
+			flat, err := tokenizer.Count(tt.model, "user: "+content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.flat, flat)
+
+			message, err := tokenizer.CountMessages(tt.model, messages)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.message, message)
+		})
+	}
+}
+
+func TestModelContextWindow(t *testing.T) {
+	for model, want := range map[tokenizer.Model]int{
+		"gpt-4-0613":                 8192,
+		"gpt-4o-mini-2024-07-18":     128000,
+		"openai/gpt-4.1-nano":        1047576,
+		"o1-mini":                    128000,
+		"claude-3-5-sonnet-20241022": 200000,
+		"llama3.1:8b":                131072,
+	} {
+		window, ok := tokenizer.ModelContextWindow(model)
+		assert.True(t, ok, model)
+		assert.Equal(t, want, window, model)
+	}
+
+	_, ok := tokenizer.ModelContextWindow("brand-new-model")
+	assert.False(t, ok)
+}
+
+func TestAssembleMessages(t *testing.T) {
+	model := tokenizer.GPT4
+	window, _ := tokenizer.ModelContextWindow(model)
+	total, err := tokenizer.CountMessages(model, history)
+	assert.NoError(t, err)
+
+	// just fits
+	messages, count, err := tokenizer.AssembleMessages(model, history, window-total)
+	assert.NoError(t, err)
+	assert.Equal(t, history, messages)
+	assert.Equal(t, total, count)
+
+	// one token short, the oldest message after the system prompt is cut
+	messages, count, err = tokenizer.AssembleMessages(model, history, window-total+1)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, count, total-1)
+	assert.Len(t, messages, len(history))
+	assert.Equal(t, history[0], messages[0])
+	assert.Equal(t, history[2:], messages[2:])
+	assert.True(t, strings.HasPrefix(history[1].Content, messages[1].Content))
+	assert.Less(t, len(messages[1].Content), len(history[1].Content))
+
+	// room for the last two messages and part of the one before
+	last, err := tokenizer.CountMessages(model, append([]tokenizer.ChatMessage{history[0]}, history[4:]...))
+	assert.NoError(t, err)
+	messages, count, err = tokenizer.AssembleMessages(model, history, window-last-8)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, count, last+8)
+	assert.Equal(t, history[0], messages[0])
+	assert.Equal(t, history[4:], messages[len(messages)-2:])
+	n, err := tokenizer.CountMessages(model, messages)
+	assert.NoError(t, err)
+	assert.Equal(t, n, count)
+
+	// not even the system prompt fits
+	_, _, err = tokenizer.AssembleMessages(model, history, window)
+	assert.ErrorIs(t, err, tokenizer.ErrContextWindowExceeded)
+
+	_, _, err = tokenizer.AssembleMessages("brand-new-model", history, 0)
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
@@ -0,0 +1,93 @@
+package tokenizer
+
+// detectEncodings are the encodings DetectEncoding tries, in the order ties
+// are broken. Encodings that extend an earlier one come after it.
+var detectEncodings = []Encoding{
+	R50kBase,
+	P50kBase,
+	Cl100kBase,
+	O200kBase,
+	OllamaLlamaBase,
+	AnthropicBase,
+	GemmaBase,
+}
+
+// detectSampleSize limits how many ids DetectEncoding looks at.
+const detectSampleSize = 512
+
+// DetectEncoding guesses which built-in encoding produced the token ids. Each
+// encoding is scored by the share of ids it can decode, times the share of
+// ids that come back unchanged when the decoded text is encoded again.
+//
+// Encodings that share ids, like r50k_base and p50k_base or cl100k_base and
+// llama, often score the same, and a smaller vocabulary tends to round-trip
+// ids of a larger one too. Ties go to the encoding listed first and lower the
+// confidence, which is the best score divided by the number of encodings
+// reaching it. An empty encoding and 0 are returned when nothing fits. Every
+// encoding is loaded, so this is meant for debugging rather than hot paths.
+func DetectEncoding(ids []uint) (Encoding, float64) {
+	if len(ids) > detectSampleSize {
+		ids = ids[:detectSampleSize]
+	}
+
+	var best Encoding
+	var bestScore float64
+	ties := 0
+	for _, encoding := range detectEncodings {
+		tok, err := Get(encoding)
+		if err != nil {
+			continue
+		}
+		score := detectScore(tok, ids)
+		switch {
+		case score == 0:
+		case score > bestScore:
+			best, bestScore, ties = encoding, score, 1
+		case score == bestScore:
+			ties++
+		}
+	}
+	if ties == 0 {
+		return "", 0
+	}
+	return best, bestScore / float64(ties)
+}
+
+func detectScore(tok Codec, ids []uint) float64 {
+	if len(ids) == 0 {
+		return 0
+	}
+
+	valid := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, err := tok.DecodeToBytes([]uint{id}); err == nil {
+			valid = append(valid, id)
+		}
+	}
+	if len(valid) == 0 {
+		return 0
+	}
+
+	text, err := tok.Decode(valid)
+	if err != nil {
+		return 0
+	}
+
+	allowed := make(map[string]bool)
+	for token := range tok.SpecialTokens() {
+		allowed[token] = true
+	}
+	encoded, _, err := tok.EncodeWithSpecialTokens(text, allowed)
+	if err != nil {
+		return 0
+	}
+
+	same := 0
+	for i := 0; i < len(valid) && i < len(encoded); i++ {
+		if valid[i] == encoded[i] {
+			same++
+		}
+	}
+
+	return float64(len(valid)) / float64(len(ids)) * float64(same) / float64(max(len(valid), len(encoded)))
+}
@@ -0,0 +1,46 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	text := "We know what we are, but know not what we may be.\n\tfunc main() {\n\t\treturn 42\n\t}"
+
+	tests := []struct {
+		encoding tokenizer.Encoding
+		// r50k_base ids are valid p50k_base ids, cl100k_base ids valid
+		// llama ids
+		confidence float64
+	}{
+		{encoding: tokenizer.R50kBase, confidence: 0.5},
+		{encoding: tokenizer.Cl100kBase, confidence: 0.5},
+		{encoding: tokenizer.GemmaBase, confidence: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			tok, err := tokenizer.Get(tt.encoding)
+			if err != nil {
+				t.Fatalf("can't create tokenizer: %v", err)
+			}
+			ids, _, err := tok.Encode(text)
+			if err != nil {
+				t.Fatalf("error encoding: %v", err)
+			}
+
+			got, confidence := tokenizer.DetectEncoding(ids)
+			assert.Equal(t, tt.encoding, got)
+			assert.InDelta(t, tt.confidence, confidence, 0.001)
+		})
+	}
+
+	for _, ids := range [][]uint{nil, {1 << 30, 1<<30 + 1}} {
+		got, confidence := tokenizer.DetectEncoding(ids)
+		assert.Equal(t, tokenizer.Encoding(""), got)
+		assert.Zero(t, confidence)
+	}
+}
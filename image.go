@@ -0,0 +1,84 @@
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// imageCost is what a tile based vision model charges per image.
+type imageCost struct {
+	base int
+	tile int
+}
+
+// imageCosts holds the published per image costs by model prefix. Models
+// without image input, or that charge per 32px patch instead of per tile,
+// have a zero cost.
+var imageCosts = map[string]imageCost{
+	"gpt-4o":               {base: 85, tile: 170},
+	"chatgpt-4o":           {base: 85, tile: 170},
+	"gpt-4o-mini":          {base: 2833, tile: 5667},
+	"gpt-4-turbo":          {base: 85, tile: 170},
+	"gpt-4-vision-preview": {base: 85, tile: 170},
+	"gpt-4.1":              {base: 85, tile: 170},
+	"gpt-4.5":              {base: 85, tile: 170},
+	"o1":                   {base: 75, tile: 150},
+	"o3":                   {base: 75, tile: 150},
+	"computer-use-preview": {base: 65, tile: 129},
+
+	"o1-mini":      {},
+	"o1-preview":   {},
+	"o3-mini":      {},
+	"gpt-4.1-mini": {},
+	"gpt-4.1-nano": {},
+	"o4-mini":      {},
+}
+
+// CountImage returns the tokens a tile based vision model charges for an
+// image of the given size. With detail "low" every image costs the base
+// tokens. With "high" the image is scaled to fit 2048x2048, then down until
+// its shortest side is at most 768px, and every 512px tile adds to the base.
+// "auto" and "" are counted as "high", the most the API can charge.
+// ErrImageNotSupported is returned for models without a known tiling cost.
+func CountImage(model Model, width, height int, detail string) (int, error) {
+	if width <= 0 || height <= 0 {
+		return 0, fmt.Errorf("invalid image size: %dx%d", width, height)
+	}
+
+	cost, ok := imageCostForModel(model)
+	if !ok {
+		return 0, ErrImageNotSupported
+	}
+
+	switch detail {
+	case "low":
+		return cost.base, nil
+	case "high", "auto", "":
+	default:
+		return 0, fmt.Errorf("invalid detail: %q", detail)
+	}
+
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+
+	return cost.base + tiles*cost.tile, nil
+}
+
+func imageCostForModel(model Model) (imageCost, bool) {
+	name := string(normalizeModel(model))
+	var cost imageCost
+	longest := -1
+	for prefix, c := range imageCosts {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			cost, longest = c, len(prefix)
+		}
+	}
+	return cost, cost.base > 0
+}
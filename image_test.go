@@ -0,0 +1,47 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountImage(t *testing.T) {
+	// the examples from OpenAI's vision guide
+	tests := []struct {
+		model         tokenizer.Model
+		width, height int
+		detail        string
+		want          int
+	}{
+		{model: tokenizer.GPT4o, width: 1024, height: 1024, detail: "high", want: 765},
+		{model: tokenizer.GPT4o, width: 2048, height: 4096, detail: "high", want: 1105},
+		{model: tokenizer.GPT4o, width: 4096, height: 8192, detail: "low", want: 85},
+		{model: "gpt-4o-2024-08-06", width: 512, height: 512, detail: "auto", want: 255},
+		{model: "gpt-4o-mini", width: 1024, height: 1024, detail: "high", want: 2833 + 4*5667},
+		{model: tokenizer.O1, width: 1024, height: 1024, detail: "high", want: 75 + 4*150},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.model), func(t *testing.T) {
+			count, err := tokenizer.CountImage(test.model, test.width, test.height, test.detail)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, count)
+		})
+	}
+}
+
+func TestCountImageErrors(t *testing.T) {
+	_, err := tokenizer.CountImage("llava", 512, 512, "high")
+	assert.ErrorIs(t, err, tokenizer.ErrImageNotSupported)
+
+	_, err = tokenizer.CountImage(tokenizer.O3Mini, 512, 512, "high")
+	assert.ErrorIs(t, err, tokenizer.ErrImageNotSupported)
+
+	_, err = tokenizer.CountImage(tokenizer.GPT4o, 512, 512, "medium")
+	assert.ErrorContains(t, err, "invalid detail")
+
+	_, err = tokenizer.CountImage(tokenizer.GPT4o, 0, 512, "high")
+	assert.ErrorContains(t, err, "invalid image size")
+}
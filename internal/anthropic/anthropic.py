@@ -4,15 +4,32 @@ import json
 import base64
 import sys
 
+
+# GPT-2 style byte level BPE stores every byte as a printable unicode
+# character (e.g. a space is "Ġ"), map them back to the raw bytes.
+def unicode_to_bytes():
+    bs = list(range(ord("!"), ord("~") + 1)) + list(range(ord("¡"), ord("¬") + 1)) + list(range(ord("®"), ord("ÿ") + 1))
+    cs = bs[:]
+    n = 0
+    for b in range(2**8):
+        if b not in bs:
+            bs.append(b)
+            cs.append(2**8 + n)
+            n += 1
+    return {chr(c): b for b, c in zip(bs, cs)}
+
+
 with open(sys.argv[1], 'r') as f:
     data = json.load(f)
 
 vocab = data['model']['vocab']
-
-startIndex = 0
+special = {t['content'] for t in data['added_tokens'] if t['special']}
+decoder = unicode_to_bytes()
 
 with open(sys.argv[2], 'w') as f:
     for token, rank in sorted(vocab.items(), key=lambda x: x[1]):
-        if rank >= startIndex:
-            token_b64 = base64.b64encode(token.encode('utf-8')).decode('ascii')
-            f.write(f"{token_b64} {rank - startIndex}\n")
\ No newline at end of file
+        if token in special:
+            continue
+        token_bytes = bytes(decoder[c] for c in token)
+        token_b64 = base64.b64encode(token_bytes).decode('ascii')
+        f.write(f"{token_b64} {rank}\n")
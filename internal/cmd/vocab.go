@@ -3,7 +3,10 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"cmp"
+	"compress/gzip"
 	"encoding/base64"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"go/format"
@@ -11,6 +14,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -23,68 +29,182 @@ type config struct {
 	url      string
 	mapName  string
 	filename string
+	encoding string
+	// dataFile is the embedded .tiktoken file, relative to the package.
+	dataFile string
+	format   vocabFormat
+	// contiguous requires the ids to run from 0 without gaps.
+	contiguous bool
 }
 
+type vocabFormat int
+
+const (
+	// formatTiktoken is the base64-word/id line format used by tiktoken.
+	formatTiktoken vocabFormat = iota
+	// formatSentencePiece is a serialized SentencePiece ModelProto
+	// (tokenizer.model).
+	formatSentencePiece
+	// formatWordPiece is a BERT style vocab.txt, one token per line with
+	// the line number as id.
+	formatWordPiece
+)
+
 func main() {
 	encoding := flag.String("encoding", "", "encoding format. (e.g. cl100k_base)")
+	sourceDir := flag.String("source", "", "directory of pre-downloaded vocabulary files, named like the last element of their URL")
+	cacheDir := flag.String("cache", defaultCacheDir(), "directory downloads are cached in and read from when fetching fails, empty to disable")
+	diff := flag.Bool("diff", false, "print how the upstream vocabulary differs from the data file instead of writing it, exiting with status 1 if it does")
 	flag.Parse()
 
-	if encoding == nil {
+	if *encoding == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
 	cfg := getConfig(*encoding)
+	cfg.encoding = *encoding
 
-	buf := new(bytes.Buffer)
-	generatePreamble(buf, *encoding)
-	generateVocabulary(buf, cfg.mapName, cfg.url)
+	data := new(bytes.Buffer)
+	generateData(data, cfg, source{dir: *sourceDir, cacheDir: *cacheDir})
 
-	src, err := format.Source(buf.Bytes())
-	if err != nil {
-		log.Fatalf("error preparing source: %v", err)
+	if *diff {
+		current, err := os.ReadFile(cfg.dataFile)
+		if err != nil {
+			log.Fatalf("error reading data file: %v", err)
+		}
+		changes, err := diffData(current, data.Bytes())
+		if err != nil {
+			log.Fatalf("error comparing %s: %v", cfg.dataFile, err)
+		}
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if len(changes) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.dataFile), 0o755); err != nil {
+		log.Fatalf("error creating data directory: %v", err)
+	}
+	if err := os.WriteFile(cfg.dataFile, data.Bytes(), 0o644); err != nil {
+		log.Fatalf("error writing data file: %v", err)
+	}
+	if err := os.WriteFile(compressedFile(cfg.dataFile), compressData(data.Bytes()), 0o644); err != nil {
+		log.Fatalf("error writing compressed data file: %v", err)
 	}
 
-	if err := os.WriteFile(cfg.filename, src, 0o644); err != nil {
-		log.Fatalf("error writing file: %v", err)
+	for _, compressed := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		generatePreamble(buf, *encoding, compressed)
+		generateLoader(buf, cfg, compressed)
+
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			log.Fatalf("error preparing source: %v", err)
+		}
+
+		filename := cfg.filename
+		if compressed {
+			filename = strings.TrimSuffix(filename, ".go") + "_gzip.go"
+		}
+		if err := os.WriteFile(filename, src, 0o644); err != nil {
+			log.Fatalf("error writing file: %v", err)
+		}
 	}
 }
 
-func generatePreamble(w io.Writer, encoding string) {
+// generatePreamble writes the header of a loader. The loaders of the plain
+// and the compressed data exclude each other with the tokenizer_gzip build
+// tag, only the plain one holds the go:generate line.
+func generatePreamble(w io.Writer, encoding string, compressed bool) {
 	fmt.Fprintf(w, "// Code generated by internal/cmd/vocab.go. DO NOT EDIT.\n\n")
-	fmt.Fprintf(w, "//go:generate go run ../internal/cmd/vocab.go -encoding %s\n\n", encoding)
+	if compressed {
+		fmt.Fprintf(w, "//go:build tokenizer_gzip\n\n")
+	} else {
+		fmt.Fprintf(w, "//go:build !tokenizer_gzip\n\n")
+		fmt.Fprintf(w, "//go:generate go run ../internal/cmd/vocab.go -encoding %s\n\n", encoding)
+	}
 	fmt.Fprintf(w, "package %s\n", packageName)
 }
 
-func generateVocabulary(w io.Writer, mapName string, uri string) {
-	var respBody io.Reader
-	if strings.HasPrefix(uri, "file:") {
-		filePath := strings.TrimPrefix(uri, "file:")
-		file, err := os.Open(filePath)
-		if err != nil {
-			log.Fatalf("error opening file: %v", err)
-		}
-		defer file.Close()
-		respBody = file
-	} else {
-		respBody = nil // will be set by http.Get
-		resp, err := http.Get(uri)
-		if err != nil {
-			log.Fatalf("error fetching file: %v", err)
-		}
-		respBody = resp.Body
-		defer resp.Body.Close()
+// generateLoader writes the code that embeds the data file, or its gzip
+// compressed copy, and parses it into the vocab map on first use.
+func generateLoader(w io.Writer, cfg config, compressed bool) {
+	mapName := cfg.mapName
+	dataFile, parse := cfg.dataFile, "mustParseVocab"
+	if compressed {
+		dataFile, parse = compressedFile(dataFile), "mustParseCompressedVocab"
 	}
 
-	fmt.Fprintf(w, "import \"sync\"\n")
+	fmt.Fprintf(w, "import (\n_ \"embed\"\n\"sync\"\n)\n")
 	fmt.Fprintf(w, "var (\n")
+	fmt.Fprintf(w, "//go:embed %s\n", dataFile)
+	fmt.Fprintf(w, "%vData string\n", mapName)
 	fmt.Fprintf(w, "%v vocab\n", mapName)
 	fmt.Fprintf(w, "%vOnce sync.Once\n", mapName)
 	fmt.Fprintf(w, ")\n")
 	fmt.Fprintf(w, "func %sInit() {\n", mapName)
-	fmt.Fprintf(w, "%s = vocab{\n", mapName)
+	fmt.Fprintf(w, "%s = %s(%sData)\n", mapName, parse, mapName)
+	fmt.Fprintf(w, "}\n")
+}
 
-	scanner := bufio.NewScanner(respBody)
+// compressedFile returns the name of the gzip compressed copy of a data
+// file.
+func compressedFile(dataFile string) string {
+	return dataFile + ".gz"
+}
+
+// compressData gzips the data. The header has no name or time, so the same
+// data always compresses to the same bytes.
+func compressData(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	zw, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if err != nil {
+		log.Fatalf("error compressing data: %v", err)
+	}
+	if _, err := zw.Write(data); err != nil {
+		log.Fatalf("error compressing data: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatalf("error compressing data: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// generateData fetches the vocabulary and writes it in the .tiktoken format,
+// one base64 encoded token and its id per line. It fails if the vocabulary
+// doesn't pass validateData.
+func generateData(w io.Writer, cfg config, src source) {
+	respBody, err := src.open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer respBody.Close()
+
+	data := new(bytes.Buffer)
+	switch cfg.format {
+	case formatSentencePiece:
+		writeSentencePiece(data, respBody)
+	case formatWordPiece:
+		writeWordPiece(data, respBody)
+	default:
+		writeTiktoken(data, respBody)
+	}
+
+	if err := validateData(data.Bytes(), cfg.contiguous); err != nil {
+		log.Fatalf("invalid vocabulary %s:\n%v", cfg.url, err)
+	}
+	if _, err := w.Write(data.Bytes()); err != nil {
+		log.Fatalf("error writing data: %v", err)
+	}
+}
+
+// writeTiktoken copies a .tiktoken file, checking every line.
+func writeTiktoken(w io.Writer, r io.Reader) {
+	scanner := bufio.NewScanner(r)
 	first := true
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -108,71 +228,423 @@ func generateVocabulary(w io.Writer, mapName string, uri string) {
 			log.Fatalf("invalid id: %q", idInput)
 		}
 
-		fmt.Fprintf(w, "%q: %d,\n", word, id)
+		writeEntry(w, string(word), id)
+	}
+}
+
+// validateData checks the generated .tiktoken data for words or ids used
+// more than once, reporting every conflicting pair of lines, and when
+// contiguous is set, for ids that don't run from 0 without gaps.
+func validateData(data []byte, contiguous bool) error {
+	var problems []string
+	words := make(map[string]int)
+	ids := make(map[uint64]int)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		word, idInput, _ := strings.Cut(line, " ")
+		id, err := strconv.ParseUint(idInput, 10, 0)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d %q: invalid id", i+1, line))
+			continue
+		}
+		if prev, ok := ids[id]; ok {
+			problems = append(problems, fmt.Sprintf("duplicate id %d: line %d %q and line %d %q", id, prev+1, lines[prev], i+1, line))
+		} else {
+			ids[id] = i
+		}
+		if prev, ok := words[word]; ok {
+			decoded, _ := base64.StdEncoding.DecodeString(word)
+			problems = append(problems, fmt.Sprintf("duplicate word %q: line %d %q and line %d %q", decoded, prev+1, lines[prev], i+1, line))
+		} else {
+			words[word] = i
+		}
+	}
+
+	if contiguous {
+		for id := range uint64(len(ids)) {
+			if _, ok := ids[id]; !ok {
+				problems = append(problems, fmt.Sprintf("ids aren't contiguous from 0: %d is missing", id))
+				break
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// diffData compares two vocabularies in the .tiktoken format and describes,
+// in id order, every word that was added (+), removed (-) or given another
+// id (~).
+func diffData(old, new []byte) ([]string, error) {
+	oldWords, err := parseData(old)
+	if err != nil {
+		return nil, fmt.Errorf("old data: %v", err)
+	}
+	newWords, err := parseData(new)
+	if err != nil {
+		return nil, fmt.Errorf("new data: %v", err)
+	}
+
+	type change struct {
+		id   uint64
+		text string
+	}
+	var changes []change
+	for word, id := range newWords {
+		oldID, ok := oldWords[word]
+		switch {
+		case !ok:
+			changes = append(changes, change{id, fmt.Sprintf("+ %q %d", word, id)})
+		case oldID != id:
+			changes = append(changes, change{id, fmt.Sprintf("~ %q %d -> %d", word, oldID, id)})
+		}
+	}
+	for word, id := range oldWords {
+		if _, ok := newWords[word]; !ok {
+			changes = append(changes, change{id, fmt.Sprintf("- %q %d", word, id)})
+		}
+	}
+	slices.SortFunc(changes, func(a, b change) int {
+		return cmp.Or(cmp.Compare(a.id, b.id), cmp.Compare(a.text, b.text))
+	})
+
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.text
+	}
+	return lines, nil
+}
+
+// parseData reads .tiktoken data into a map of words to ids.
+func parseData(data []byte) (map[string]uint64, error) {
+	words := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		wordInput, idInput, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line: %q", scanner.Text())
+		}
+		word, err := base64.StdEncoding.DecodeString(wordInput)
+		if err != nil {
+			return nil, fmt.Errorf("invalid word: %q", wordInput)
+		}
+		id, err := strconv.ParseUint(idInput, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %q", idInput)
+		}
+		words[string(word)] = id
+	}
+	return words, scanner.Err()
+}
+
+// source decides where the vocabulary of a config is read from.
+type source struct {
+	// dir holds pre-downloaded files, used instead of fetching the URL.
+	dir string
+	// cacheDir keeps a copy of every download, used when fetching fails.
+	cacheDir string
+}
+
+// open returns the vocabulary for the config. A file: URL is read as is,
+// otherwise the file is taken from the source directory when one is set, or
+// fetched and cached, falling back to the cache when fetching fails.
+func (s source) open(cfg config) (io.ReadCloser, error) {
+	if filePath, ok := strings.CutPrefix(cfg.url, "file:"); ok {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %v", err)
+		}
+		return file, nil
+	}
+
+	name := path.Base(cfg.url)
+	if s.dir != "" {
+		file, err := os.Open(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("error opening source file: %v", err)
+		}
+		return file, nil
+	}
+
+	// llama and gemma both download a tokenizer.model, so the cache is split
+	// by encoding
+	cached := ""
+	if s.cacheDir != "" {
+		cached = filepath.Join(s.cacheDir, cfg.encoding, name)
+	}
+
+	data, err := fetch(cfg.url)
+	if err != nil {
+		if cached == "" {
+			return nil, err
+		}
+		file, cacheErr := os.Open(cached)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("%v, and no cached copy at %s", err, cached)
+		}
+		log.Printf("%v, using cached copy %s", err, cached)
+		return file, nil
+	}
+
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+			return nil, fmt.Errorf("error creating cache directory: %v", err)
+		}
+		if err := os.WriteFile(cached, data, 0o644); err != nil {
+			return nil, fmt.Errorf("error writing cache: %v", err)
+		}
 	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
 
-	fmt.Fprintf(w, "}\n}\n")
+func fetch(uri string) ([]byte, error) {
+	resp, err := http.Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: %s", uri, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %v", uri, err)
+	}
+	return data, nil
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-tokenizer")
+}
+
+func writeEntry(w io.Writer, word string, id uint64) {
+	fmt.Fprintf(w, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(word)), id)
 }
 
 func getConfig(encoding string) config {
 	switch encoding {
 	case "o200k_base":
 		return config{
-			mapName:  "o200kBaseVocab",
-			url:      "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
-			filename: "o200k_base_vocab.go",
+			mapName:    "o200kBaseVocab",
+			url:        "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+			filename:   "o200k_base_vocab.go",
+			dataFile:   "data/o200k_base.tiktoken",
+			contiguous: true,
 		}
 	case "cl100k_base":
 		return config{
-			mapName:  "cl100kBaseVocab",
-			url:      "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
-			filename: "cl100k_base_vocab.go",
+			mapName:    "cl100kBaseVocab",
+			url:        "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+			filename:   "cl100k_base_vocab.go",
+			dataFile:   "data/cl100k_base.tiktoken",
+			contiguous: true,
 		}
 	case "r50k_base":
 		return config{
-			mapName:  "r50kBaseVocab",
-			url:      "https://openaipublic.blob.core.windows.net/encodings/r50k_base.tiktoken",
-			filename: "r50k_base_vocab.go",
+			mapName:    "r50kBaseVocab",
+			url:        "https://openaipublic.blob.core.windows.net/encodings/r50k_base.tiktoken",
+			filename:   "r50k_base_vocab.go",
+			dataFile:   "data/r50k_base.tiktoken",
+			contiguous: true,
 		}
 	case "p50k_base":
 		return config{
 			mapName:  "p50kBaseVocab",
 			url:      "https://openaipublic.blob.core.windows.net/encodings/p50k_base.tiktoken",
 			filename: "p50k_base_vocab.go",
+			dataFile: "data/p50k_base.tiktoken",
 		}
 	case "llama":
 		return config{
-			mapName:  "llamaBaseVocab",
-			url:      "https://raw.githubusercontent.com/meta-llama/llama-models/refs/heads/main/models/llama3/tokenizer.model",
-			filename: "llama_base_vocab.go",
+			mapName:    "llamaVocab",
+			url:        "https://raw.githubusercontent.com/meta-llama/llama-models/refs/heads/main/models/llama3/tokenizer.model",
+			filename:   "llama3_base_vocab.go",
+			dataFile:   "data/llama3_base.tiktoken",
+			contiguous: true,
 		}
-	case "gpt2":
+	case "gemma":
 		return config{
-			mapName:  "gpt2BaseVocab",
-			url:      "https://openaipublic.blob.core.windows.net/encodings/r50k_base.tiktoken",
-			filename: "gpt2_base_vocab.go",
+			mapName:  "gemmaVocab",
+			url:      "https://github.com/google/gemma_pytorch/raw/refs/heads/main/tokenizer/tokenizer.model",
+			filename: "gemma_base_vocab.go",
+			dataFile: "data/gemma_base.tiktoken",
+			format:   formatSentencePiece,
 		}
 	case "anthropic":
 		return config{
 			mapName: "anthropicVocab",
 			// https://huggingface.co/Xenova/claude-tokenizer/blob/main/tokenizer.json
-			url:      "file:internal/anthropic/anthropic.tiktoken",
+			url:      "file:../internal/anthropic/anthropic.tiktoken",
 			filename: "anthropic_base_vocab.go",
+			dataFile: "data/anthropic_base.tiktoken",
+		}
+	case "sentencepiece":
+		return config{
+			mapName: "sentencePieceVocab",
+			// Llama 2 tokenizer, mirrored outside the gated meta-llama repos
+			url:      "https://huggingface.co/hf-internal-testing/llama-tokenizer/resolve/main/tokenizer.model",
+			filename: "sentencepiece_base_vocab.go",
+			dataFile: "data/sentencepiece_base.tiktoken",
+			format:   formatSentencePiece,
+		}
+	case "mistral":
+		return config{
+			mapName: "mistralVocab",
+			// the v1 tokenizer of Mistral 7B v0.1 and v0.2 and Mixtral 8x7B
+			url:      "https://huggingface.co/mistralai/Mistral-7B-v0.1/resolve/main/tokenizer.model",
+			filename: "mistral_base_vocab.go",
+			dataFile: "data/mistral_base.tiktoken",
+			format:   formatSentencePiece,
+		}
+	case "bert":
+		return config{
+			mapName:    "bertBaseVocab",
+			url:        "https://huggingface.co/google-bert/bert-base-uncased/resolve/main/vocab.txt",
+			filename:   "bert_base_vocab.go",
+			dataFile:   "data/bert_base.tiktoken",
+			format:     formatWordPiece,
+			contiguous: true,
 		}
-	// case "sentencepiece":
-	// 	return config{
-	// 		mapName:  "sentencePieceVocab",
-	// 		url:      "https://github.com/google/gemma_pytorch/raw/refs/heads/main/tokenizer/tokenizer.model",
-	// 		filename: "sentencepiece_vocab.go",
-	// 	}
-	// case "bert":
-	// 	return config{
-	// 		mapName:  "bertVocab",
-	// 		url:      "https://raw.githubusercontent.com/google-research/bert/master/vocab.txt",
-	// 		filename: "bert_vocab.go",
-	// 	}
 	default:
 		log.Fatal("config not found")
 		return config{}
 	}
 }
+
+// SentencePiece piece types, see sentencepiece_model.proto.
+const (
+	pieceNormal      = 1
+	pieceUnknown     = 2
+	pieceControl     = 3
+	pieceUserDefined = 4
+	pieceUnused      = 5
+	pieceByte        = 6
+)
+
+type sentencePiece struct {
+	piece     string
+	pieceType uint64
+}
+
+// writeSentencePiece reads a serialized SentencePiece ModelProto and writes
+// its normal, user defined and byte pieces as vocab entries with their
+// position as id. Control and unknown pieces are left for the codec
+// constructor to register as special tokens.
+func writeSentencePiece(w io.Writer, r io.Reader) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		log.Fatalf("error reading model: %v", err)
+	}
+
+	pieces, err := parseModelProto(data)
+	if err != nil {
+		log.Fatalf("error parsing model: %v", err)
+	}
+
+	for id, p := range pieces {
+		switch p.pieceType {
+		case pieceNormal, pieceUserDefined, pieceByte:
+			writeEntry(w, p.piece, uint64(id))
+		}
+	}
+}
+
+// parseModelProto decodes the repeated pieces field of a ModelProto. The
+// remaining fields (trainer and normalizer specs) are skipped.
+func parseModelProto(data []byte) ([]sentencePiece, error) {
+	var pieces []sentencePiece
+	err := walkProto(data, func(field int, value []byte, _ uint64) error {
+		if field != 1 {
+			return nil
+		}
+		p := sentencePiece{pieceType: pieceNormal}
+		err := walkProto(value, func(field int, value []byte, varint uint64) error {
+			switch field {
+			case 1:
+				p.piece = string(value)
+			case 3:
+				p.pieceType = varint
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		pieces = append(pieces, p)
+		return nil
+	})
+	return pieces, err
+}
+
+// walkProto calls fn for every field in a protobuf message. Length-delimited
+// and fixed-width fields are passed as value, varints as varint.
+func walkProto(data []byte, fn func(field int, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+
+		field, wireType := int(key>>3), key&7
+		var value []byte
+		var varint uint64
+		switch wireType {
+		case 0:
+			varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint in field %d", field)
+			}
+			data = data[n:]
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			value, data = data[:size], data[size:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			value, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wireType, field)
+		}
+
+		if err := fn(field, value, varint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWordPiece reads a vocab.txt and writes every token with its line
+// number as id. Special tokens such as [CLS] stay in the vocab, the codec
+// constructor registers them too.
+func writeWordPiece(w io.Writer, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	var id uint64
+	for scanner.Scan() {
+		writeEntry(w, strings.TrimSuffix(scanner.Text(), "\r"), id)
+		id++
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("error reading vocab.txt: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"go/format"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const tinyData = "IQ== 0\naGVsbG8= 1\nIHdvcmxk 2\n"
+
+func TestGenerateFile(t *testing.T) {
+	cfg := config{
+		url:      "file:testdata/tiny.tiktoken",
+		mapName:  "tinyVocab",
+		encoding: "tiny",
+		dataFile: "data/tiny.tiktoken",
+	}
+
+	data := new(bytes.Buffer)
+	generateData(data, cfg, source{})
+	if data.String() != tinyData {
+		t.Fatalf("data = %q, want %q", data.String(), tinyData)
+	}
+
+	for _, tt := range []struct {
+		compressed bool
+		want       []string
+	}{
+		{false, []string{
+			"//go:build !tokenizer_gzip",
+			"//go:generate go run ../internal/cmd/vocab.go -encoding tiny",
+			"//go:embed data/tiny.tiktoken\n",
+			"tinyVocab = mustParseVocab(tinyVocabData)",
+		}},
+		{true, []string{
+			"//go:build tokenizer_gzip",
+			"//go:embed data/tiny.tiktoken.gz\n",
+			"tinyVocab = mustParseCompressedVocab(tinyVocabData)",
+		}},
+	} {
+		buf := new(bytes.Buffer)
+		generatePreamble(buf, cfg.encoding, tt.compressed)
+		generateLoader(buf, cfg, tt.compressed)
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			t.Fatalf("generated invalid source: %v", err)
+		}
+		for _, want := range tt.want {
+			if !bytes.Contains(src, []byte(want)) {
+				t.Errorf("generated source is missing %q:\n%s", want, src)
+			}
+		}
+		if tt.compressed && bytes.Contains(src, []byte("go:generate")) {
+			t.Errorf("compressed loader runs the generator again:\n%s", src)
+		}
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressData(data.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != tinyData {
+		t.Errorf("compressed data = %q, want %q", decompressed, tinyData)
+	}
+}
+
+func TestSourceDir(t *testing.T) {
+	cfg := config{url: "http://127.0.0.1:0/tiny.tiktoken", encoding: "tiny"}
+
+	data := new(bytes.Buffer)
+	generateData(data, cfg, source{dir: "testdata"})
+	if data.String() != tinyData {
+		t.Fatalf("data = %q, want %q", data.String(), tinyData)
+	}
+}
+
+func TestSourceCache(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/tiny.tiktoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	src := source{cacheDir: cacheDir}
+	cfg := config{url: server.URL + "/tiny.tiktoken", encoding: "tiny"}
+
+	read := func() (string, error) {
+		r, err := src.open(cfg)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+
+	// a successful download is cached
+	got, err := read()
+	if err != nil || got != string(fixture) {
+		t.Fatalf("open() = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "tiny", "tiny.tiktoken")); err != nil {
+		t.Fatalf("download not cached: %v", err)
+	}
+
+	// and used when fetching fails
+	fail = true
+	got, err = read()
+	if err != nil || got != string(fixture) {
+		t.Fatalf("open() from cache = %q, %v", got, err)
+	}
+
+	// without a cache the error says so
+	src.cacheDir = t.TempDir()
+	if _, err := read(); err == nil || !strings.Contains(err.Error(), "no cached copy") {
+		t.Fatalf("open() error = %v, want a missing cache error", err)
+	}
+}
+
+func TestValidateData(t *testing.T) {
+	fixture, err := os.Open("testdata/duplicate_id.tiktoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fixture.Close()
+
+	data := new(bytes.Buffer)
+	writeTiktoken(data, fixture)
+	err = validateData(data.Bytes(), false)
+	want := `duplicate id 1: line 2 "aGVsbG8= 1" and line 3 "IHdvcmxk 1"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("validateData() error = %v, want %q", err, want)
+	}
+
+	for _, tt := range []struct {
+		data       string
+		contiguous bool
+		want       string
+	}{
+		{tinyData, true, ""},
+		{"IQ== 0\naGVsbG8= 1\nIQ== 2\n", false, `duplicate word "!": line 1 "IQ== 0" and line 3 "IQ== 2"`},
+		{"IQ== 0\naGVsbG8= 2\n", false, ""},
+		{"IQ== 0\naGVsbG8= 2\n", true, "ids aren't contiguous from 0: 1 is missing"},
+		{"IQ== 1\naGVsbG8= 2\n", true, "ids aren't contiguous from 0: 0 is missing"},
+	} {
+		err := validateData([]byte(tt.data), tt.contiguous)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("validateData(%q, %v) error = %v", tt.data, tt.contiguous, err)
+			}
+		} else if err == nil || err.Error() != tt.want {
+			t.Errorf("validateData(%q, %v) error = %v, want %q", tt.data, tt.contiguous, err, tt.want)
+		}
+	}
+}
+
+// TestValidateEmbeddedData checks the data files checked into the codec
+// package pass the validation they were generated with.
+func TestValidateEmbeddedData(t *testing.T) {
+	for _, encoding := range []string{"o200k_base", "cl100k_base", "r50k_base", "p50k_base", "llama", "gemma", "anthropic"} {
+		cfg := getConfig(encoding)
+		data, err := os.ReadFile(filepath.Join("../../codec", cfg.dataFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := validateData(data, cfg.contiguous); err != nil {
+			t.Errorf("%s: %v", encoding, err)
+		}
+	}
+}
+
+func TestGenerateWordPiece(t *testing.T) {
+	cfg := config{url: "file:testdata/tiny_vocab.txt", format: formatWordPiece, contiguous: true}
+
+	data := new(bytes.Buffer)
+	generateData(data, cfg, source{})
+	want := "W1BBRF0= 0\nW1VOS10= 1\naGVsbG8= 2\nIyNz 3\n"
+	if data.String() != want {
+		t.Fatalf("data = %q, want %q", data.String(), want)
+	}
+}
+
+func TestDiffData(t *testing.T) {
+	current := new(bytes.Buffer)
+	generateData(current, config{url: "file:testdata/tiny.tiktoken"}, source{})
+	upstream := new(bytes.Buffer)
+	generateData(upstream, config{url: "file:testdata/tiny_drift.tiktoken"}, source{})
+
+	changes, err := diffData(current.Bytes(), upstream.Bytes())
+	if err != nil {
+		t.Fatalf("diffData() error: %v", err)
+	}
+	want := []string{
+		`+ "hi" 1`,
+		`- "hello" 1`,
+		`~ " world" 2 -> 3`,
+		`+ " there" 4`,
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("diffData() = %q, want %q", changes, want)
+	}
+
+	changes, err = diffData(current.Bytes(), current.Bytes())
+	if err != nil || len(changes) != 0 {
+		t.Errorf("diffData() of the same data = %q, %v, want no changes", changes, err)
+	}
+}
@@ -0,0 +1,53 @@
+package tokenizer
+
+import "strings"
+
+const (
+	// messageOverhead is the number of tokens OpenAI's chat format adds
+	// around every message.
+	messageOverhead = 3
+	// replyOverhead is the number of tokens that prime the reply.
+	replyOverhead = 3
+)
+
+// CountMessages estimates the prompt tokens of a chat request with the
+// messages. Claude models are counted with CountAnthropicMessages, with the
+// system messages as the system prompt. Other models are counted the way
+// OpenAI documents for its chat models: each message is its role and content
+// plus 3 tokens of framing, and 3 more prime the reply.
+func CountMessages(model Model, messages []ChatMessage) (int, error) {
+	encoding, err := EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+	if encoding == AnthropicBase {
+		var system []string
+		var rest []ChatMessage
+		for _, m := range messages {
+			if m.Role == "system" {
+				system = append(system, m.Content)
+			} else {
+				rest = append(rest, m)
+			}
+		}
+		return CountAnthropicMessages(model, strings.Join(system, "\n\n"), rest)
+	}
+
+	enc, err := Get(encoding)
+	if err != nil {
+		return 0, err
+	}
+	count := replyOverhead
+	for _, m := range messages {
+		role, err := enc.Count(m.Role)
+		if err != nil {
+			return 0, err
+		}
+		content, err := enc.Count(m.Content)
+		if err != nil {
+			return 0, err
+		}
+		count += messageOverhead + role + content
+	}
+	return count, nil
+}
@@ -0,0 +1,27 @@
+package tokenizer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeModelFamilies(t *testing.T) {
+	// the built-in maps only repeat prefixes with the same encoding
+	if _, conflicts := mergeModelFamilies(modelFamilies); len(conflicts) > 0 {
+		t.Fatalf("conflicting model prefixes: %v", conflicts)
+	}
+
+	families := append(slices.Clip(modelFamilies), modelFamily{"testModels", map[string]Encoding{
+		"llama3": Cl100kBase,
+		"gemma":  GemmaBase,
+	}})
+	result, conflicts := mergeModelFamilies(families)
+
+	want := `"llama3": llama in llamaModels, cl100k_base in testModels`
+	if len(conflicts) != 1 || conflicts[0] != want {
+		t.Fatalf("conflicts = %q, want [%q]", conflicts, want)
+	}
+	if result["llama3"] != Cl100kBase {
+		t.Errorf("llama3 = %s, want the last family's cl100k_base", result["llama3"])
+	}
+}
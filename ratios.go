@@ -1,5 +1,57 @@
 package tokenizer
 
+import "strings"
+
 var Ratios = map[string]float64{
 	"claude-": 1.166,
 }
+
+// RatioCodec is a Codec whose Count is scaled by the ratio of a model, like
+// the package level Count. Everything else is passed through unchanged.
+type RatioCodec struct {
+	Codec
+	Ratio float64
+}
+
+// Count returns the token count of the underlying Codec scaled by Ratio.
+func (c *RatioCodec) Count(input string) (int, error) {
+	raw, err := c.Codec.Count(input)
+	if err != nil {
+		return 0, err
+	}
+	return c.adjust(raw), nil
+}
+
+func (c *RatioCodec) adjust(raw int) int {
+	return int(float64(raw) * c.Ratio)
+}
+
+// ForModelWithRatio is like ForModel, but the returned Codec is a
+// *RatioCodec counting like Count does for the model.
+func ForModelWithRatio(model Model) (Codec, error) {
+	return forModelWithRatio(model)
+}
+
+func forModelWithRatio(model Model) (*RatioCodec, error) {
+	model = normalizeModel(model)
+	enc, err := ForModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return &RatioCodec{Codec: enc, Ratio: modelRatio(model)}, nil
+}
+
+// modelRatio returns the entry in Ratios for the model, or 1.
+func modelRatio(model Model) float64 {
+	// ignore provider segments like "anthropic/"
+	name := string(model)
+	for alias := modelProvider.ReplaceAllString(name, ""); alias != name; alias = modelProvider.ReplaceAllString(name, "") {
+		name = alias
+	}
+	for prefix, ratio := range Ratios {
+		if strings.HasPrefix(name, prefix) {
+			return ratio
+		}
+	}
+	return 1
+}
@@ -0,0 +1,54 @@
+package tokenizer
+
+// bosTokens and eosTokens are the conventional begin and end of sequence
+// tokens by codec name. Llama 3 chat models end a turn with <|eot_id|>
+// rather than <|end_of_text|>. GPT-2 uses <|endoftext|> for both.
+var (
+	bosTokens = map[string]string{
+		"llama":         "<|begin_of_text|>",
+		"gemma":         "<bos>",
+		"sentencepiece": "<s>",
+		"mistral":       "<s>",
+		"gpt2":          "<|endoftext|>",
+		"r50k_base":     "<|endoftext|>",
+	}
+	eosTokens = map[string]string{
+		"llama":         "<|eot_id|>",
+		"gemma":         "<eos>",
+		"sentencepiece": "</s>",
+		"mistral":       "</s>",
+		"gpt2":          "<|endoftext|>",
+		"r50k_base":     "<|endoftext|>",
+		"p50k_base":     "<|endoftext|>",
+		"p50k_edit":     "<|endoftext|>",
+		"cl100k_base":   "<|endoftext|>",
+		"o200k_base":    "<|endoftext|>",
+	}
+)
+
+// BOSTokenID returns the id of the begin of sequence token of the model's
+// codec, e.g. <|begin_of_text|> for Llama 3. It is false if the codec has
+// none, as OpenAI's encodings don't.
+func BOSTokenID(model Model) (uint, bool, error) {
+	return specialTokenID(model, bosTokens)
+}
+
+// EOSTokenID returns the id of the end of sequence token of the model's
+// codec, e.g. <|eot_id|> for Llama 3 and <|endoftext|> for the OpenAI
+// encodings. It is false if the codec has none.
+func EOSTokenID(model Model) (uint, bool, error) {
+	return specialTokenID(model, eosTokens)
+}
+
+func specialTokenID(model Model, tokens map[string]string) (uint, bool, error) {
+	enc, err := ForModel(model)
+	if err != nil {
+		return 0, false, err
+	}
+	token, ok := tokens[enc.GetName()]
+	if !ok {
+		return 0, false, nil
+	}
+	id, ok := enc.SpecialTokens()[token]
+	return id, ok, nil
+}
@@ -0,0 +1,40 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/awee-ai/go-tokenizer"
+)
+
+func TestBOSEOSTokenID(t *testing.T) {
+	for _, tt := range []struct {
+		model          tokenizer.Model
+		bos, eos       uint
+		hasBOS, hasEOS bool
+	}{
+		{model: "llama3.1:8b", bos: 128000, eos: 128009, hasBOS: true, hasEOS: true},
+		{model: tokenizer.Davinci, bos: 50256, eos: 50256, hasBOS: true, hasEOS: true},
+		{model: "gemma2", bos: 2, eos: 1, hasBOS: true, hasEOS: true},
+		{model: "llama2:7b", bos: 1, eos: 2, hasBOS: true, hasEOS: true},
+		{model: "mistral:7b", bos: 1, eos: 2, hasBOS: true, hasEOS: true},
+		{model: tokenizer.GPT4o, eos: 199999, hasEOS: true},
+		{model: "claude-sonnet-4"},
+	} {
+		t.Run(string(tt.model), func(t *testing.T) {
+			bos, ok, err := tokenizer.BOSTokenID(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.hasBOS, ok)
+			assert.Equal(t, tt.bos, bos)
+
+			eos, ok, err := tokenizer.EOSTokenID(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.hasEOS, ok)
+			assert.Equal(t, tt.eos, eos)
+		})
+	}
+
+	_, _, err := tokenizer.EOSTokenID("brand-new-model")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
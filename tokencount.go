@@ -0,0 +1,38 @@
+package tokenizer
+
+// TokenCount is a number of tokens, for budgeting and cost estimates. The
+// methods keep counts from being mixed up with other ints.
+type TokenCount int
+
+// Add returns the sum of the counts.
+func (c TokenCount) Add(other TokenCount) TokenCount {
+	return c + other
+}
+
+// Sub returns the count minus other, which is negative if other is larger.
+func (c TokenCount) Sub(other TokenCount) TokenCount {
+	return c - other
+}
+
+// WithinBudget reports whether the count doesn't exceed max tokens.
+func (c TokenCount) WithinBudget(max int) bool {
+	return int(c) <= max
+}
+
+// CostUSD returns the price of the tokens at pricePer1K dollars per 1000
+// tokens.
+func (c TokenCount) CostUSD(pricePer1K float64) float64 {
+	return float64(c) * pricePer1K / 1000
+}
+
+// CountTokens is Count returning a TokenCount.
+func CountTokens(model Model, input string) (TokenCount, error) {
+	count, err := Count(model, input)
+	return TokenCount(count), err
+}
+
+// CountMessageTokens is CountMessages returning a TokenCount.
+func CountMessageTokens(model Model, messages []ChatMessage) (TokenCount, error) {
+	count, err := CountMessages(model, messages)
+	return TokenCount(count), err
+}
@@ -0,0 +1,43 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/awee-ai/go-tokenizer"
+)
+
+func TestTokenCount(t *testing.T) {
+	prompt, completion := tokenizer.TokenCount(1200), tokenizer.TokenCount(300)
+
+	total := prompt.Add(completion)
+	assert.Equal(t, tokenizer.TokenCount(1500), total)
+	assert.Equal(t, prompt, total.Sub(completion))
+	assert.Equal(t, tokenizer.TokenCount(-900), completion.Sub(prompt))
+
+	assert.True(t, total.WithinBudget(1500))
+	assert.False(t, total.WithinBudget(1499))
+	assert.True(t, tokenizer.TokenCount(0).WithinBudget(0))
+
+	assert.InDelta(t, 0.006, prompt.CostUSD(0.005), 1e-12)
+	assert.InDelta(t, 0.0045, completion.CostUSD(0.015), 1e-12)
+	assert.Zero(t, tokenizer.TokenCount(0).CostUSD(0.03))
+}
+
+func TestCountTokens(t *testing.T) {
+	count, err := tokenizer.Count(tokenizer.GPT4o, "hello world")
+	assert.NoError(t, err)
+	tokens, err := tokenizer.CountTokens(tokenizer.GPT4o, "hello world")
+	assert.NoError(t, err)
+	assert.Equal(t, tokenizer.TokenCount(count), tokens)
+
+	count, err = tokenizer.CountMessages(tokenizer.GPT4o, history)
+	assert.NoError(t, err)
+	tokens, err = tokenizer.CountMessageTokens(tokenizer.GPT4o, history)
+	assert.NoError(t, err)
+	assert.Equal(t, tokenizer.TokenCount(count), tokens)
+
+	_, err = tokenizer.CountTokens("brand-new-model", "hello")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
@@ -11,10 +11,18 @@ package tokenizer
 // - P50kBase
 // - P50kEdit
 // - LLamaBase
+// - AnthropicBase
+// - GemmaBase
+// - BertBase
+// - SentencePieceBase
+// - MistralBase
 // - GPT2Enc [hope to support]
 //
 // Alternatively you can request a tokenizer using OpenAI's model name, the
 // following OpenAI models are supported:
+// - GPT5
+// - GPT5Mini
+// - GPT5Nano
 // - O4Mini
 // - O3
 // - O3Mini
@@ -23,6 +31,7 @@ package tokenizer
 // - O1Mini
 // - GPT4.1
 // - GPT4o
+// - GPT4oMini
 // - GPT4
 // - GPT35Turbo
 // - TextEmbeddingAda002
@@ -56,6 +65,19 @@ package tokenizer
 // - CodeDavinciEdit001
 // - LLama3
 //
+// Whitespace
+//
+// Runs of whitespace are split differently per encoding, so indented code and
+// aligned tables can count very differently. In all the tiktoken style
+// encodings the last space of a run is kept with the following word, the rest
+// of the run becomes its own piece. R50kBase has no multi-space tokens and
+// spends one token per space, P50kBase and P50kEdit add single tokens for
+// runs of 2 to 25 spaces, Cl100kBase, O200kBase and LLamaBase have tokens for
+// longer runs of spaces and tabs. Cl100kBase keeps whitespace after a newline
+// at the end of the input together with the newline, like tiktoken does.
+// GemmaBase encodes a whole run of spaces as a single token and the word after
+// it without a leading space.
+//
 // Usage Example
 //
 // Here is an example of how to encode a string using the `ForModel` function:
@@ -84,7 +106,12 @@ package tokenizer
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/awee-ai/go-tokenizer/codec"
 )
@@ -92,13 +119,51 @@ import (
 var (
 	ErrModelNotSupported    = errors.New("model not supported")
 	ErrEncodingNotSupported = errors.New("encoding not supported")
+	ErrImageNotSupported    = errors.New("image input not supported")
+	// ErrEmptyModel is returned for an empty or whitespace only model name,
+	// which isn't treated as an unsupported model.
+	ErrEmptyModel = errors.New("empty model name")
 )
 
+// UnsupportedModelError is returned for a model no encoding is known for. It
+// matches ErrModelNotSupported with errors.Is.
+type UnsupportedModelError struct {
+	Model Model
+}
+
+func (e *UnsupportedModelError) Error() string {
+	return fmt.Sprintf("model not supported: %q", string(e.Model))
+}
+
+func (e *UnsupportedModelError) Unwrap() error {
+	return ErrModelNotSupported
+}
+
+// UnsupportedEncodingError is returned for an unknown encoding. It matches
+// ErrEncodingNotSupported with errors.Is.
+type UnsupportedEncodingError struct {
+	Encoding Encoding
+}
+
+func (e *UnsupportedEncodingError) Error() string {
+	return fmt.Sprintf("encoding not supported: %q", string(e.Encoding))
+}
+
+func (e *UnsupportedEncodingError) Unwrap() error {
+	return ErrEncodingNotSupported
+}
+
 type Codec interface {
 	GetName() string
 	Count(string) (int, error)
 	Encode(string) ([]uint, []string, error)
+	EncodeOrdinary(string) ([]uint, error)
+	EncodeWithSpecialTokens(string, map[string]bool) ([]uint, []string, error)
+	Pattern() string
+	SpecialTokens() map[string]uint
 	Decode([]uint) (string, error)
+	DecodeToBytes([]uint) ([]byte, error)
+	DecodeTo(io.Writer, []uint) (int, error)
 }
 
 type Model string
@@ -110,8 +175,12 @@ const (
 	O3                       Model = "o3"
 	O3Mini                   Model = "o3-mini"
 	O4Mini                   Model = "o4-mini"
+	GPT5                     Model = "gpt-5"
+	GPT5Mini                 Model = "gpt-5-mini"
+	GPT5Nano                 Model = "gpt-5-nano"
 	GPT41                    Model = "gpt-4.1"
 	GPT4o                    Model = "gpt-4o"
+	GPT4oMini                Model = "gpt-4o-mini"
 	GPT4                     Model = "gpt-4"
 	GPT35Turbo               Model = "gpt-3.5-turbo"
 	GPT35                    Model = "gpt-3.5"
@@ -149,14 +218,18 @@ const (
 type Encoding string
 
 const (
-	R50kBase        Encoding = "r50k_base"   // OpenAI GPT-2 base tokenizer (same as above)
-	P50kBase        Encoding = "p50k_base"   // Codex tokenizer variant (GPT-style BPE, 50k)
-	P50kEdit        Encoding = "p50k_edit"   // Used by OpenAI's edit models
-	Cl100kBase      Encoding = "cl100k_base" // GPT-4/GPT-3.5 Turbo tokenizer (100k BPE)
-	O200kBase       Encoding = "o200k_base"  // OpenAI 200k tokenizer (e.g. GPT-4o, o1, o3)
-	OllamaLlamaBase Encoding = "llama"       // LLaMA3 tokenizer (BPE, 200k vocab, used by LLama3+ models)
-	AnthropicBase   Encoding = "cl100k_base" // Anthropic tokenizer (Claude family, 100k vocab)
-	GeminiBase      Encoding = "cl100k_base" // Gemini tokenizer (200k vocab, tiktoken-compatible)
+	R50kBase          Encoding = "r50k_base"     // OpenAI GPT-2 base tokenizer (same as above)
+	P50kBase          Encoding = "p50k_base"     // Codex tokenizer variant (GPT-style BPE, 50k)
+	P50kEdit          Encoding = "p50k_edit"     // Used by OpenAI's edit models
+	Cl100kBase        Encoding = "cl100k_base"   // GPT-4/GPT-3.5 Turbo tokenizer (100k BPE)
+	O200kBase         Encoding = "o200k_base"    // OpenAI 200k tokenizer (e.g. GPT-4o, o1, o3)
+	OllamaLlamaBase   Encoding = "llama"         // LLaMA3 tokenizer (BPE, 200k vocab, used by LLama3+ models)
+	AnthropicBase     Encoding = "anthropic"     // Anthropic tokenizer (Claude family, 65k vocab)
+	GemmaBase         Encoding = "gemma"         // Gemma tokenizer (SentencePiece, 256k vocab)
+	GeminiBase        Encoding = "gemma"         // Gemini tokenizer (shares the Gemma vocab)
+	BertBase          Encoding = "bert"          // BERT tokenizer (WordPiece, 30k vocab, used by BERT based embedding models)
+	SentencePieceBase Encoding = "sentencepiece" // Llama 2 tokenizer (SentencePiece, 32k vocab, used by Llama 2 based models)
+	MistralBase       Encoding = "mistral"       // Mistral v1 tokenizer (SentencePiece, 32k vocab, used by Mistral 7B and Mixtral)
 	// anthropic tests
 	// r50k_base
 	// expected: 91
@@ -225,11 +298,11 @@ var llamaModels = map[string]Encoding{
 	"llama3":   OllamaLlamaBase,
 	"llama4":   OllamaLlamaBase,
 
-	// Llama 2 family uses SentencePiece - fallback to GPT-2 style for now
-	"llama2":            R50kBase, // MIGRATION: Should be SentencePiece
-	"codellama":         R50kBase, // Based on Llama 2, MIGRATION: Should be SentencePiece
-	"llama2-uncensored": R50kBase, // MIGRATION: Should be SentencePiece
-	"llama2-chinese":    R50kBase, // MIGRATION: Should be SentencePiece
+	// Llama 2 family uses SentencePiece with a 32k vocab
+	"llama2":            SentencePieceBase,
+	"codellama":         SentencePieceBase, // Based on Llama 2
+	"llama2-uncensored": SentencePieceBase,
+	"llama2-chinese":    SentencePieceBase,
 }
 
 // Qwen family - custom tiktoken-compatible implementation
@@ -246,12 +319,9 @@ var qwenModels = map[string]Encoding{
 }
 
 var claudeModels = map[string]Encoding{
-	"claude-2.0": Cl100kBase,
-	"claude-2.1": Cl100kBase,
+	"claude-2.0": AnthropicBase,
+	"claude-2.1": AnthropicBase,
 
-	// Cl100kBase
-	// expected: 49
-	// actual  : 41
 	"claude-3-opus-":     AnthropicBase,
 	"claude-3-sonnet-":   AnthropicBase,
 	"claude-3-5-sonnet-": AnthropicBase,
@@ -263,57 +333,63 @@ var claudeModels = map[string]Encoding{
 	"claude-sonnet-4": AnthropicBase,
 }
 
-// Mistral family - mixed tokenizers (older=SentencePiece, newer=Tekken/tiktoken)
+// Mistral family - mixed tokenizers (older=SentencePiece, newer=Tekken)
 var mistralModels = map[string]Encoding{
-	"mistral":          R50kBase, // MIGRATION: Older versions use SentencePiece
-	"mistral-nemo":     R50kBase, // Uses Tekken (tiktoken-based)
-	"mistral-small":    R50kBase, // MIGRATION: Older versions use SentencePiece
-	"mistral-small3.1": R50kBase, // Uses Tekken
-	"mistral-small3.2": R50kBase, // Uses Tekken
-	"mistral-large":    R50kBase, // MIGRATION: Check version
-	"mistral-openorca": R50kBase, // MIGRATION: Likely SentencePiece
-	"mistrallite":      R50kBase, // MIGRATION: Likely SentencePiece
-	"mathstral":        R50kBase, // MIGRATION: Likely SentencePiece
-	"codestral":        R50kBase, // MIGRATION: Likely SentencePiece
-	"devstral":         R50kBase, // Recent, likely Tekken
-	"mixtral":          R50kBase, // MIGRATION: SentencePiece
+	// Mistral 7B, Mixtral and the other pre-Nemo models use SentencePiece with
+	// a 32k vocab. v0.3 and the 8x22B generation add 768 control tokens in
+	// front of the same pieces, so counts match but their ids are shifted.
+	"mistral":          MistralBase,
+	"mistral-large":    MistralBase,
+	"mistral-openorca": MistralBase,
+	"mistrallite":      MistralBase,
+	"mathstral":        MistralBase,
+	"codestral":        MistralBase,
+	"mixtral":          MistralBase,
+
+	// Mistral Nemo and later use Tekken, a tiktoken style BPE with a 131k
+	// vocab that isn't embedded yet, so they fall back to GPT-2 style
+	"mistral-nemo":     R50kBase,
+	"mistral-small":    R50kBase, // Tekken since Mistral Small 3
+	"mistral-small3.1": R50kBase,
+	"mistral-small3.2": R50kBase,
+	"devstral":         R50kBase,
+	"magistral":        R50kBase,
 }
 
 // Gemma family - SentencePiece with BPE
 var gemmaModels = map[string]Encoding{
-	"gemma3n":   R50kBase, // MIGRATION: Should be SentencePiece
-	"gemma3":    R50kBase, // MIGRATION: Should be SentencePiece
-	"gemma2":    R50kBase, // MIGRATION: Should be SentencePiece
-	"gemma":     R50kBase, // MIGRATION: Should be SentencePiece
-	"codegemma": R50kBase, // MIGRATION: Should be SentencePiece
+	"gemma3n":   GemmaBase,
+	"gemma3":    GemmaBase,
+	"gemma2":    GemmaBase,
+	"gemma":     GemmaBase,
+	"codegemma": GemmaBase,
 }
 
 var geminiModels = map[string]Encoding{
 	"gemini-": GeminiBase,
-	// "gemini-": R50kBase,
 }
 
 // Phi family - tokenizer changed between versions
 var phiModels = map[string]Encoding{
-	"phi3":                OllamaLlamaBase,
-	"phi4":                R50kBase, // Uses tiktoken (100k vocab)
-	"phi4-mini":           R50kBase, // Uses tiktoken
-	"phi4-reasoning":      R50kBase, // Uses tiktoken
-	"phi4-mini-reasoning": R50kBase, // Uses tiktoken
-	"phi3.5":              R50kBase, // MIGRATION: Uses SentencePiece
-	"phi":                 R50kBase, // Phi-2 uses CodeGen tokenizer
+	"phi3":                SentencePieceBase, // Llama 2 SentencePiece vocab plus chat tokens
+	"phi4":                Cl100kBase,        // cl100k_base plus ChatML tokens (100k vocab)
+	"phi4-mini":           O200kBase,         // o200k_base plus chat tokens (200k vocab)
+	"phi4-reasoning":      Cl100kBase,        // Same tokenizer as phi4
+	"phi4-mini-reasoning": O200kBase,         // Same tokenizer as phi4-mini
+	"phi3.5":              SentencePieceBase, // Same tokenizer as phi3
+	"phi":                 R50kBase,          // Phi-2 uses CodeGen tokenizer
 }
 
 // Vision/Multimodal models - inherit from base model
 var visionModels = map[string]Encoding{
-	"llava":             R50kBase,        // MIGRATION: Depends on base (Llama 2 = SentencePiece)
-	"llava-llama3":      OllamaLlamaBase, // Based on Llama 3
-	"llava-phi3":        R50kBase,        // MIGRATION: Based on Phi-3 (SentencePiece)
-	"minicpm-v":         R50kBase,        // MIGRATION: Likely SentencePiece
-	"llama3.2-vision":   OllamaLlamaBase, // Based on Llama 3.2
-	"bakllava":          R50kBase,        // MIGRATION: Based on Mistral (SentencePiece)
-	"moondream":         R50kBase,        // Custom small model
-	"granite3.2-vision": R50kBase,        // IBM custom
+	"llava":             SentencePieceBase, // Based on Vicuna (Llama 2)
+	"llava-llama3":      OllamaLlamaBase,   // Based on Llama 3
+	"llava-phi3":        SentencePieceBase, // Based on Phi-3
+	"minicpm-v":         R50kBase,          // MIGRATION: Likely SentencePiece
+	"llama3.2-vision":   OllamaLlamaBase,   // Based on Llama 3.2
+	"bakllava":          MistralBase,       // Based on Mistral
+	"moondream":         R50kBase,          // Custom small model
+	"granite3.2-vision": R50kBase,          // IBM custom
 }
 
 // Granite family - IBM models
@@ -333,20 +409,20 @@ var graniteModels = map[string]Encoding{
 var smallModels = map[string]Encoding{
 	"smollm2":     R50kBase,
 	"smollm":      R50kBase,
-	"tinyllama":   R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"phi":         R50kBase, // Phi-2
-	"tinydolphin": R50kBase, // MIGRATION: Based on TinyLlama (SentencePiece)
+	"tinyllama":   SentencePieceBase, // Based on Llama 2
+	"phi":         R50kBase,          // Phi-2
+	"tinydolphin": SentencePieceBase, // Based on TinyLlama
 }
 
 // Embedding models - typically simpler tokenizers
 var embeddingModels = map[string]Encoding{
-	"nomic-embed-text":        R50kBase, // MIGRATION: Likely custom/SentencePiece
-	"mxbai-embed-large":       R50kBase,
+	"nomic-embed-text":        BertBase, // bert-base-uncased vocab
+	"mxbai-embed-large":       BertBase,
 	"bge-m3":                  R50kBase,
-	"snowflake-arctic-embed":  R50kBase,
+	"snowflake-arctic-embed":  BertBase,
 	"snowflake-arctic-embed2": R50kBase,
-	"all-minilm":              R50kBase,
-	"bge-large":               R50kBase,
+	"all-minilm":              BertBase,
+	"bge-large":               BertBase,
 	"paraphrase-multilingual": R50kBase,
 }
 
@@ -354,45 +430,45 @@ var embeddingModels = map[string]Encoding{
 var derivedModels = map[string]Encoding{
 	// Dolphin family - inherits from base model
 	"dolphin3":        OllamaLlamaBase, // Based on Llama 3.1
-	"dolphin-mixtral": R50kBase,        // MIGRATION: Based on Mixtral (SentencePiece)
-	"dolphin-mistral": R50kBase,        // MIGRATION: Based on Mistral (SentencePiece)
+	"dolphin-mixtral": MistralBase,     // Based on Mixtral
+	"dolphin-mistral": MistralBase,     // Based on Mistral
 	"dolphin-llama3":  OllamaLlamaBase, // Based on Llama 3
 	"dolphincoder":    R50kBase,        // MIGRATION: Based on StarCoder (custom)
 	"dolphin-phi":     R50kBase,        // MIGRATION: Based on Phi (CodeGen)
 
 	// Hermes family
-	"hermes3":              OllamaLlamaBase, // Based on Llama 3.1
-	"nous-hermes2":         R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"nous-hermes2-mixtral": R50kBase,        // MIGRATION: Based on Mixtral (SentencePiece)
-	"nous-hermes":          R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"openhermes":           R50kBase,        // MIGRATION: Based on Mistral (SentencePiece)
+	"hermes3":              OllamaLlamaBase,   // Based on Llama 3.1
+	"nous-hermes2":         SentencePieceBase, // Based on Llama 2
+	"nous-hermes2-mixtral": MistralBase,       // Based on Mixtral
+	"nous-hermes":          SentencePieceBase, // Based on Llama 2
+	"openhermes":           MistralBase,       // Based on Mistral
 
 	// Wizard family
-	"wizardlm2":                R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizardlm":                 R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizardlm-uncensored":      R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizardcoder":              R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizard-math":              R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizard-vicuna-uncensored": R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"wizard-vicuna":            R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
+	"wizardlm2":                SentencePieceBase, // Based on Llama 2
+	"wizardlm":                 SentencePieceBase, // Based on Llama 2
+	"wizardlm-uncensored":      SentencePieceBase, // Based on Llama 2
+	"wizardcoder":              SentencePieceBase, // Based on Llama 2
+	"wizard-math":              SentencePieceBase, // Based on Llama 2
+	"wizard-vicuna-uncensored": SentencePieceBase, // Based on Llama 2
+	"wizard-vicuna":            SentencePieceBase, // Based on Llama 2
 }
 
 // Everything else - fallback models
 var fallbackModels = map[string]Encoding{
 	"starcoder2":          R50kBase, // Custom StarCoder tokenizer
 	"starcoder":           R50kBase,
-	"orca-mini":           R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"orca2":               R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"yi":                  R50kBase, // Custom Yi tokenizer
+	"orca-mini":           SentencePieceBase, // Based on Llama 2
+	"orca2":               SentencePieceBase, // Based on Llama 2
+	"yi":                  R50kBase,          // Custom Yi tokenizer
 	"yi-coder":            R50kBase,
-	"zephyr":              R50kBase, // MIGRATION: Based on Mistral (SentencePiece)
-	"command-r":           R50kBase, // Cohere custom
+	"zephyr":              MistralBase, // Based on Mistral
+	"command-r":           R50kBase,    // Cohere custom
 	"command-r-plus":      R50kBase,
 	"command-r7b":         R50kBase,
 	"command-r7b-arabic":  R50kBase,
 	"command-a":           R50kBase,
-	"vicuna":              R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"openchat":            R50kBase, // MIGRATION: Based on Mistral (SentencePiece)
+	"vicuna":              SentencePieceBase, // Based on Llama 2
+	"openchat":            MistralBase,       // Based on Mistral
 	"olmo2":               R50kBase,
 	"dbrx":                R50kBase,
 	"falcon":              R50kBase,
@@ -403,33 +479,33 @@ var fallbackModels = map[string]Encoding{
 	"stablelm2":           R50kBase,
 	"stablelm-zephyr":     R50kBase,
 	"stable-code":         R50kBase,
-	"stable-beluga":       R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
+	"stable-beluga":       SentencePieceBase, // Based on Llama 2
 	"sqlcoder":            R50kBase,
 	"reflection":          OllamaLlamaBase, // Based on Llama 3.1
 	"starling-lm":         R50kBase,
-	"xwinlm":              R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"phind-codellama":     R50kBase, // MIGRATION: Based on Code Llama (SentencePiece)
+	"xwinlm":              SentencePieceBase, // Based on Llama 2
+	"phind-codellama":     SentencePieceBase, // Based on Code Llama
 	"internlm2":           R50kBase,
-	"yarn-llama2":         R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"yarn-mistral":        R50kBase, // MIGRATION: Based on Mistral (SentencePiece)
-	"nexusraven":          R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"shieldgemma":         R50kBase, // MIGRATION: Based on Gemma (SentencePiece)
-	"everythinglm":        R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"codeup":              R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
+	"yarn-llama2":         SentencePieceBase, // Based on Llama 2
+	"yarn-mistral":        MistralBase,       // Based on Mistral
+	"nexusraven":          SentencePieceBase, // Based on Llama 2
+	"shieldgemma":         GemmaBase,         // Based on Gemma
+	"everythinglm":        SentencePieceBase, // Based on Llama 2
+	"codeup":              SentencePieceBase, // Based on Llama 2
 	"duckdb-nsql":         R50kBase,
 	"magicoder":           R50kBase,
-	"codebooga":           R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
+	"codebooga":           SentencePieceBase, // Based on Llama 2
 	"bespoke-minicheck":   R50kBase,
-	"tulu3":               OllamaLlamaBase, // Based on Llama 3.1
-	"nuextract":           R50kBase,        // Based on Phi-3
-	"megadolphin":         R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"notux":               R50kBase,        // MIGRATION: Based on Mixtral (SentencePiece)
-	"open-orca-platypus2": R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"notus":               R50kBase,        // MIGRATION: Based on Zephyr/Mistral (SentencePiece)
-	"goliath":             R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"alfred":              R50kBase,        // MIGRATION: Based on Llama 2 (SentencePiece)
-	"neural-chat":         R50kBase,        // MIGRATION: Based on Mistral (SentencePiece)
-	"samantha-mistral":    R50kBase,        // MIGRATION: Based on Mistral (SentencePiece)
+	"tulu3":               OllamaLlamaBase,   // Based on Llama 3.1
+	"nuextract":           SentencePieceBase, // Based on Phi-3
+	"megadolphin":         SentencePieceBase, // Based on Llama 2
+	"notux":               MistralBase,       // Based on Mixtral
+	"open-orca-platypus2": SentencePieceBase, // Based on Llama 2
+	"notus":               MistralBase,       // Based on Zephyr/Mistral
+	"goliath":             SentencePieceBase, // Based on Llama 2
+	"alfred":              SentencePieceBase, // Based on Llama 2
+	"neural-chat":         MistralBase,       // Based on Mistral
+	"samantha-mistral":    MistralBase,       // Based on Mistral
 	"athene-v2":           R50kBase,
 	"nemotron-mini":       R50kBase,
 	"nemotron":            OllamaLlamaBase, // Based on Llama 3.1
@@ -443,76 +519,80 @@ var fallbackModels = map[string]Encoding{
 	"firefunction-v2":     OllamaLlamaBase, // Based on Llama 3
 	"codegeex4":           R50kBase,
 	"glm4":                R50kBase,
-	"meditron":            R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
-	"medllama2":           R50kBase, // MIGRATION: Based on Llama 2 (SentencePiece)
+	"meditron":            SentencePieceBase, // Based on Llama 2
+	"medllama2":           SentencePieceBase, // Based on Llama 2
 	"reader-lm":           R50kBase,
 	"r1-1776":             R50kBase, // Based on DeepSeek R1
 	"marco-o1":            R50kBase,
 	"openthinker":         R50kBase, // Based on DeepSeek R1
-	"magistral":           R50kBase,
 	"cogito":              R50kBase,
 }
 
-// Combine all maps into the main lookup
-func buildModelPrefixToEncoding() map[string]Encoding {
-	result := make(map[string]Encoding)
+// modelFamily is one of the model prefix maps, named for reporting
+// conflicts.
+type modelFamily struct {
+	name   string
+	models map[string]Encoding
+}
 
-	// Add all maps in order of priority (more specific to less specific)
-	for k, v := range definitiveTokenizerFamilies {
-		result[k] = v
-	}
-	for k, v := range claudeModels {
-		result[k] = v
-	}
-	for k, v := range deepSeekModels {
-		result[k] = v
-	}
-	for k, v := range llamaModels {
-		result[k] = v
-	}
-	for k, v := range qwenModels {
-		result[k] = v
-	}
-	for k, v := range mistralModels {
-		result[k] = v
-	}
-	for k, v := range gemmaModels {
-		result[k] = v
-	}
-	for k, v := range geminiModels {
-		result[k] = v
-	}
-	for k, v := range phiModels {
-		result[k] = v
-	}
-	for k, v := range visionModels {
-		result[k] = v
-	}
-	for k, v := range graniteModels {
-		result[k] = v
-	}
-	for k, v := range smallModels {
-		result[k] = v
-	}
-	for k, v := range embeddingModels {
-		result[k] = v
-	}
-	for k, v := range derivedModels {
-		result[k] = v
-	}
-	for k, v := range fallbackModels {
-		result[k] = v
-	}
+// modelFamilies are combined into the main lookup in order, more specific to
+// less specific.
+var modelFamilies = []modelFamily{
+	{"definitiveTokenizerFamilies", definitiveTokenizerFamilies},
+	{"claudeModels", claudeModels},
+	{"deepSeekModels", deepSeekModels},
+	{"llamaModels", llamaModels},
+	{"qwenModels", qwenModels},
+	{"mistralModels", mistralModels},
+	{"gemmaModels", gemmaModels},
+	{"geminiModels", geminiModels},
+	{"phiModels", phiModels},
+	{"visionModels", visionModels},
+	{"graniteModels", graniteModels},
+	{"smallModels", smallModels},
+	{"embeddingModels", embeddingModels},
+	{"derivedModels", derivedModels},
+	{"fallbackModels", fallbackModels},
+}
 
+// Combine all maps into the main lookup, panicking if two of them map the
+// same prefix to different encodings, so a conflicting entry is caught by
+// any test run instead of silently taking the last value.
+func buildModelPrefixToEncoding() map[string]Encoding {
+	result, conflicts := mergeModelFamilies(modelFamilies)
+	if len(conflicts) > 0 {
+		panic("conflicting model prefixes:\n" + strings.Join(conflicts, "\n"))
+	}
 	return result
 }
 
+// mergeModelFamilies combines the families into one map and describes every
+// prefix that two families map to different encodings.
+func mergeModelFamilies(families []modelFamily) (map[string]Encoding, []string) {
+	result := make(map[string]Encoding)
+	from := make(map[string]string)
+	var conflicts []string
+	for _, family := range families {
+		for prefix, encoding := range family.models {
+			if prev, ok := result[prefix]; ok && prev != encoding {
+				conflicts = append(conflicts, fmt.Sprintf("%q: %s in %s, %s in %s", prefix, prev, from[prefix], encoding, family.name))
+			}
+			result[prefix] = encoding
+			from[prefix] = family.name
+		}
+	}
+	slices.Sort(conflicts)
+	return result, conflicts
+}
+
 var modelPrefixToEncoding = buildModelPrefixToEncoding()
 
 // Get returns a new instance of a Codec implementation based on the specified
 // encoding format. The returned Codec instance can be used to encode (tokenize)
 // and decode (reassemble) text. If the specified encoding is not supported,
-// an error is returned.
+// an error is returned. Every call returns a new Codec that shares no mutable
+// state with the others, so special tokens added to one are never seen by
+// another.
 func Get(encoding Encoding) (Codec, error) {
 	switch encoding {
 	case O200kBase:
@@ -527,68 +607,271 @@ func Get(encoding Encoding) (Codec, error) {
 		return codec.NewP50kEdit(), nil
 	case OllamaLlamaBase:
 		return codec.NewLLama3Base(), nil
-	// large margin of error
-	// case AnthropicBase:
-	// 	return codec.NewAnthropicBase(), nil
+	case AnthropicBase:
+		return codec.NewAnthropicBase(), nil
+	case GemmaBase:
+		return codec.NewGemmaBase(), nil
+	case BertBase:
+		return codec.NewBertBase(), nil
+	case SentencePieceBase:
+		return codec.NewSentencePieceBase(), nil
+	case MistralBase:
+		return codec.NewMistralBase(), nil
 	default:
-		return nil, ErrEncodingNotSupported
+		return nil, &UnsupportedEncodingError{Encoding: encoding}
 	}
 }
 
+// Warmup loads the vocabularies of the encodings concurrently, so the first
+// Get for them doesn't pay for parsing a vocabulary of up to 256k entries.
+// Servers can call it at startup. It returns the errors of the encodings
+// that aren't supported, joined.
+func Warmup(encodings ...Encoding) error {
+	errs := make([]error, len(encodings))
+	var wg sync.WaitGroup
+	for i, encoding := range encodings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = Get(encoding)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 // ForModel returns a new instance of a Codec implementation based on the
 // specified OpenAI model. If the specified model is not supported, an error
-// is returned.
+// is returned, unless a default encoding was set with SetDefaultEncoding.
 func ForModel(model Model) (Codec, error) {
-	switch model {
-	case O1, O1Preview, O1Mini, GPT41, GPT4o, O3, O3Mini, O4Mini:
-		return Get(O200kBase)
+	encoding, err := EncodingForModel(model)
+	if errors.Is(err, ErrModelNotSupported) {
+		if fallback := DefaultEncoding(); fallback != "" {
+			return useFallback(model, fallback)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Get(encoding)
+}
+
+// ForModelOrDefault is like ForModel, but returns a Codec for the fallback
+// encoding when the model is not supported.
+func ForModelOrDefault(model Model, fallback Encoding) (Codec, error) {
+	encoding, err := EncodingForModel(model)
+	if errors.Is(err, ErrModelNotSupported) {
+		return useFallback(model, fallback)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Get(encoding)
+}
+
+var (
+	defaultEncodingMu sync.RWMutex
+	defaultEncoding   Encoding
+	onFallback        func(model Model, encoding Encoding)
+)
+
+// SetDefaultEncoding makes ForModel and Count use the encoding for models
+// they don't know instead of returning ErrModelNotSupported. An empty
+// encoding turns the fallback off again.
+func SetDefaultEncoding(encoding Encoding) {
+	defaultEncodingMu.Lock()
+	defer defaultEncodingMu.Unlock()
+	defaultEncoding = encoding
+}
+
+// DefaultEncoding returns the encoding set with SetDefaultEncoding.
+func DefaultEncoding() Encoding {
+	defaultEncodingMu.RLock()
+	defer defaultEncodingMu.RUnlock()
+	return defaultEncoding
+}
+
+// SetOnFallback sets a function that is called every time a fallback
+// encoding is used for an unsupported model, so callers can log or flag the
+// count as an estimate. A nil fn removes it.
+func SetOnFallback(fn func(model Model, encoding Encoding)) {
+	defaultEncodingMu.Lock()
+	defer defaultEncodingMu.Unlock()
+	onFallback = fn
+}
+
+func useFallback(model Model, encoding Encoding) (Codec, error) {
+	defaultEncodingMu.RLock()
+	fn := onFallback
+	defaultEncodingMu.RUnlock()
+	if fn != nil {
+		fn(model, encoding)
+	}
+	return Get(encoding)
+}
+
+// EncodingForModel returns the Encoding ForModel picks for the model. Known
+// OpenAI models are matched exactly, any other model by the longest known
+// model prefix. When the full name is unknown, snapshot suffixes such as
+// -20250805, -2024-08-06, -v2 or -latest are stripped and the family base is
+// matched instead. Gateway style names are resolved by dropping leading
+// provider segments, so openai/gpt-4o, anthropic/claude-3-5-sonnet and
+// bedrock/anthropic.claude-3-sonnet-v1 all resolve. Model names are matched
+// case-insensitively, surrounding whitespace is ignored, and so are tags and
+// versions like the :8b in llama3.1:8b or the @20240620 of Vertex AI names.
+func EncodingForModel(model Model) (Encoding, error) {
+	if normalizeModel(model) == "" {
+		return "", ErrEmptyModel
+	}
+	_, encoding, ok := ResolveModel(model)
+	if !ok {
+		return "", &UnsupportedModelError{Model: model}
+	}
+	return encoding, nil
+}
+
+// ResolveModel reports how EncodingForModel resolves the model: the known
+// model name or prefix that matched and its encoding. It is meant for
+// debugging unexpected encodings.
+func ResolveModel(model Model) (prefix string, encoding Encoding, ok bool) {
+	name := string(normalizeModel(model))
+	for {
+		if prefix, encoding, ok := snapshotEncoding(name); ok {
+			return prefix, encoding, true
+		}
+		alias := modelProvider.ReplaceAllString(name, "")
+		if alias == name {
+			return "", "", false
+		}
+		name = alias
+	}
+}
+
+// NormalizeModel returns the canonical model for a model name and its
+// encoding: the name lowercased and without provider segments, tags and
+// snapshot suffixes, so "openrouter/GPT-4o-2024-08-06" becomes gpt-4o.
+// Suffixes are only stripped down to a known model, deepseek-v3 stays as it
+// is. The error is an *UnsupportedModelError if the model can't be resolved.
+func NormalizeModel(s string) (Model, Encoding, error) {
+	encoding, err := EncodingForModel(Model(s))
+	if err != nil {
+		return "", "", err
+	}
+
+	name := string(normalizeModel(Model(s)))
+	for alias := modelProvider.ReplaceAllString(name, ""); alias != name; alias = modelProvider.ReplaceAllString(name, "") {
+		name = alias
+	}
+	for {
+		if prefix, _, ok := modelEncoding(name); ok && prefix == name {
+			break
+		}
+		base := modelSuffix.ReplaceAllString(name, "")
+		if base == name {
+			break
+		}
+		name = base
+	}
+	return Model(name), encoding, nil
+}
+
+// snapshotEncoding matches the name, stripping snapshot suffixes until a
+// match is found.
+func snapshotEncoding(name string) (string, Encoding, bool) {
+	for {
+		if prefix, encoding, ok := modelEncoding(name); ok {
+			return prefix, encoding, true
+		}
+		base := modelSuffix.ReplaceAllString(name, "")
+		if base == name {
+			return "", "", false
+		}
+		name = base
+	}
+}
+
+// normalizeModel lowercases the model and drops surrounding whitespace and a
+// :tag or @version suffix, as in llama3.1:8b or claude-3-5-sonnet@20240620.
+// The ft: prefix of fine-tuned models is kept.
+func normalizeModel(model Model) Model {
+	name := strings.ToLower(strings.TrimSpace(string(model)))
+	rest, fineTuned := strings.CutPrefix(name, "ft:")
+	if i := strings.IndexAny(rest, ":@"); i >= 0 {
+		rest = rest[:i]
+	}
+	if fineTuned {
+		rest = "ft:" + rest
+	}
+	return Model(rest)
+}
+
+// modelSuffix matches the date, version and alias suffixes of model snapshots.
+// modelProvider matches a leading provider segment such as "openai/" or
+// "anthropic.". Dotted segments are letters only, so "gpt-4.1" is left alone.
+var modelProvider = regexp.MustCompile(`^([a-z0-9_-]+/|[a-z]+\.)`)
+
+var modelSuffix = regexp.MustCompile(`-(\d{8}|\d{4}-\d{2}-\d{2}|\d{4}|v\d+(\.\d+)*|latest)$`)
+
+func modelEncoding(name string) (string, Encoding, bool) {
+	switch Model(name) {
+	case O1, O1Preview, O1Mini, GPT41, GPT4o, GPT4oMini, O3, O3Mini, O4Mini,
+		GPT5, GPT5Mini, GPT5Nano:
+		return name, O200kBase, true
 
 	case GPT4, GPT35, GPT35Turbo, TextEmbeddingAda002:
-		return Get(Cl100kBase)
+		return name, Cl100kBase, true
 
 	case TextDavinci003, TextDavinci002, CodeDavinci001,
 		CodeDavinci002, CodeCushman002, CodeCushman001,
 		DavinciCodex, CushmanCodex:
-		return Get(P50kBase)
+		return name, P50kBase, true
 
 	case TextDavinci001, TextCurie001, TextBabbage001, TextAda001, Davinci,
 		Curie, Babbage, Ada, TextSimilarityDavinci001, TextSimilarityCurie001,
 		TextSimilarityBabbage001, TextSimilarityAda001, TextSearchDavinciDoc001,
 		TextSearchCurieDoc001, TextSearchAdaDoc001, TextSearchBabbageDoc001,
 		CodeSearchBabbageCode001, CodeSearchAdaCode001:
-		return Get(R50kBase)
+		return name, R50kBase, true
 
 	case TextDavinciEdit001, CodeDavinciEdit001:
-		return Get(P50kEdit)
+		return name, P50kEdit, true
+	}
 
-	default:
-		for prefix, enc := range modelPrefixToEncoding {
-			if strings.HasPrefix(string(model), string(prefix)) {
-				// panic("found prefix: " + prefix + " for model: " + string(model))
-				return Get(enc)
-			}
+	// prefixes ending in a dash, like "claude-3-opus-", also match the bare
+	// family name
+	var match string
+	for prefix := range modelPrefixToEncoding {
+		if len(prefix) > len(match) && strings.HasPrefix(name+"-", prefix) {
+			match = prefix
 		}
-		return nil, ErrModelNotSupported
 	}
+	if match == "" {
+		return "", "", false
+	}
+	return match, modelPrefixToEncoding[match], true
 }
 
+// Count returns the number of tokens the model's encoding splits the input
+// into, scaled by the model's entry in Ratios. It measures the input as one
+// flat string: count chat requests with CountMessages or
+// CountAnthropicMessages rather than prefixing each message with its role,
+// which counts the role as content and misses the framing tokens.
 func Count(model Model, input string) (int, error) {
-	enc, err := ForModel(model)
+	_, count, _, err := CountDetailed(model, input)
+	return count, err
+}
+
+// CountDetailed is like Count but also returns the token count of the
+// underlying encoding and the ratio applied to it. The ratio is 1 for models
+// without an entry in Ratios.
+func CountDetailed(model Model, input string) (raw int, adjusted int, ratio float64, err error) {
+	enc, err := forModelWithRatio(model)
 	if err != nil {
-		return 0, err
+		return 0, 0, 0, err
 	}
-	count, err := enc.Count(input)
+	raw, err = enc.Codec.Count(input)
 	if err != nil {
-		return 0, err
-	}
-
-	// account ratios
-	for prefix, ratio := range Ratios {
-		if strings.HasPrefix(string(model), string(prefix)) {
-			count = int(float64(count) * ratio)
-			break
-		}
+		return 0, 0, 0, err
 	}
-
-	return count, nil
+	return raw, enc.adjust(raw), enc.Ratio, nil
 }
@@ -2,17 +2,21 @@ package tokenizer_test
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/awee-ai/go-tokenizer"
+	"github.com/awee-ai/go-tokenizer/codec"
 )
 
 type testCase struct {
@@ -75,14 +79,14 @@ func Test_AnthropicModel_TokenCounts(t *testing.T) {
 			model:               "claude-3-7-sonnet-20250219",
 			body:                body2,
 			expectedRemoteCount: 91,
-			expectedLocalCount:  93,
+			expectedLocalCount:  90,
 		},
 		{
 			name:                "claude-opus-4-1-20250805",
 			model:               "claude-opus-4-1-20250805",
 			body:                body2,
 			expectedRemoteCount: 91,
-			expectedLocalCount:  93,
+			expectedLocalCount:  90,
 		},
 		// diff 28
 		{
@@ -90,35 +94,35 @@ func Test_AnthropicModel_TokenCounts(t *testing.T) {
 			model:               "claude-opus-4-20250514",
 			body:                body2,
 			expectedRemoteCount: 91,
-			expectedLocalCount:  93,
+			expectedLocalCount:  90,
 		},
 		{
 			name:                "claude-sonnet-4-20250514",
 			model:               "claude-sonnet-4-20250514",
 			body:                body,
 			expectedRemoteCount: 49,
-			expectedLocalCount:  47,
+			expectedLocalCount:  49,
 		},
 		{
 			name:                "claude-3-7-sonnet-20250219",
 			model:               "claude-3-7-sonnet-20250219",
 			body:                body,
 			expectedRemoteCount: 49,
-			expectedLocalCount:  47,
+			expectedLocalCount:  49,
 		},
 		{
 			name:                "claude-3-5-sonnet-20241022",
 			model:               "claude-3-5-sonnet-20241022",
 			body:                body,
 			expectedRemoteCount: 49,
-			expectedLocalCount:  47,
+			expectedLocalCount:  49,
 		},
 		{
 			name:                "claude-3-5-haiku-20241022",
 			model:               "claude-3-5-haiku-20241022",
 			body:                body,
 			expectedRemoteCount: 49,
-			expectedLocalCount:  47,
+			expectedLocalCount:  49,
 		},
 	}
 
@@ -155,26 +159,132 @@ func Test_AnthropicModel_TokenCounts(t *testing.T) {
 			assert.NoError(t, err, "failed to unmarshal response body")
 			assert.Equal(t, tt.expectedRemoteCount, response.InputTokens, "Remote token count mismatch for model %s", tt.model)
 
-			count, err := tokenizer.Count(tokenizer.Model(tt.model), tt.body.Messages[0].Role+": "+tt.body.Messages[0].Content)
+			count, err := tokenizer.CountAnthropicMessages(tokenizer.Model(tt.model), "", []tokenizer.ChatMessage{{Role: tt.body.Messages[0].Role, Content: tt.body.Messages[0].Content}})
 			assert.NoError(t, err, "failed to locally count tokens for model %s", tt.model)
 			assert.Equal(t, tt.expectedLocalCount, count, "Local token count mismatch for model %s", tt.model)
 		})
 	}
 }
 
+func TestCountAnthropicMessages(t *testing.T) {
+	content := "This tool uses Anthropic's newly released token counting api to count the number of tokens in a given text. Beware of existing tokenizers which are not accurate. Explore the source code here."
+
+	// remote counts captured from Test_AnthropicModel_TokenCounts
+	tests := []struct {
+		model  tokenizer.Model
+		text   string
+		remote int
+	}{
+		{model: "claude-sonnet-4-20250514", text: content, remote: 49},
+		{model: "claude-3-5-haiku-20241022", text: content, remote: 49},
+		{model: "claude-opus-4-1-20250805", text: content + " " + content, remote: 91},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			count, err := tokenizer.CountAnthropicMessages(tt.model, "", []tokenizer.ChatMessage{{Role: "user", Content: tt.text}})
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.remote, count, 1)
+		})
+	}
+
+	withSystem, err := tokenizer.CountAnthropicMessages("claude-sonnet-4-20250514", "Be brief.", []tokenizer.ChatMessage{{Role: "user", Content: content}})
+	assert.NoError(t, err)
+	assert.Greater(t, withSystem, 49)
+
+	_, err = tokenizer.CountAnthropicMessages(tokenizer.GPT4o, "", nil)
+	assert.ErrorContains(t, err, "not an Anthropic model")
+	_, err = tokenizer.CountAnthropicMessages("claude-sonnet-4", "", []tokenizer.ChatMessage{{Role: "tool", Content: "x"}})
+	assert.ErrorContains(t, err, "unsupported role")
+}
+
+// Test_AnthropicBase_TokenCount counts the message as a flat "user: "
+// string scaled by the claude- ratio, which only approximates the API:
+// CountAnthropicMessages frames it the way the API does, see
+// TestCountAnthropicMessages.
+func Test_AnthropicBase_TokenCount(t *testing.T) {
+	content := "This tool uses Anthropic's newly released token counting api to count the number of tokens in a given text. Beware of existing tokenizers which are not accurate. Explore the source code here."
+
+	// remote counts captured from Test_AnthropicModel_TokenCounts
+	tests := []testCase2{
+		{
+			name:                "claude-sonnet-4-20250514",
+			model:               "claude-sonnet-4-20250514",
+			text:                "user: " + content,
+			expectedRemoteCount: 49,
+			expectedLocalCount:  50,
+		},
+		{
+			name:                "claude-3-5-haiku-20241022",
+			model:               "claude-3-5-haiku-20241022",
+			text:                "user: " + content,
+			expectedRemoteCount: 49,
+			expectedLocalCount:  50,
+		},
+		{
+			name:                "claude-opus-4-1-20250805",
+			model:               "claude-opus-4-1-20250805",
+			text:                "user: " + content + " " + content,
+			expectedRemoteCount: 91,
+			expectedLocalCount:  97,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := tokenizer.Count(tokenizer.Model(tt.model), tt.text)
+			assert.NoError(t, err, "failed to count tokens for model %s", tt.model)
+			assert.Equal(t, tt.expectedLocalCount, count, "Local token count mismatch for model %s", tt.model)
+		})
+	}
+}
+
+func TestAnthropicBase(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.AnthropicBase)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	if name := tok.GetName(); name != "anthropic" {
+		t.Fatalf("unexpected tokenizer name: %s", name)
+	}
+
+	for _, text := range []string{
+		"hello world",
+		"hello  world",
+		"hello   world",
+		"supercalifragilistic",
+		"We know what we are, but know not what we may be.",
+	} {
+		ids, _, err := tok.Encode(text)
+		if err != nil {
+			t.Fatalf("error encoding: %v", err)
+		}
+		decoded, err := tok.Decode(ids)
+		if err != nil {
+			t.Fatalf("error decoding: %v", err)
+		}
+		if decoded != text {
+			t.Errorf("decoding mismatch - want: %s got: %s", text, decoded)
+		}
+	}
+}
+
 func Test_Model_TokenCount(t *testing.T) {
 	tests := []testCase2{
-		// {
-		// 	name:          "gpt-5",
-		// 	model:         "gpt-5",
-		// 	text:          "OpenAI's large language models process text using tokens, which are common sequences of characters found in a set of text. The models learn to understand the statistical relationships between these tokens, and excel at producing the next token in a sequence of tokens. Learn more.",
-		// 	expectedRemoteCount: 52,
-		// },
+		{
+			name:                "gpt-5",
+			model:               "gpt-5",
+			text:                "OpenAI's large language models process text using tokens, which are common sequences of characters found in a set of text. The models learn to understand the statistical relationships between these tokens, and excel at producing the next token in a sequence of tokens. Learn more.",
+			expectedRemoteCount: 52,
+		},
 		{
 			name:                "claude-opus-4",
 			model:               "claude-opus-4",
 			text:                "This tool uses Anthropic's newly released token counting api to count the number of tokens in a given text. Beware of existing tokenizers which are not accurate. Explore the source code here.",
 			expectedRemoteCount: 45,
+			// the claude- ratio is tuned for longer texts
+			expectedLocalCount: 47,
 		},
 		{
 			name:                "gemini-1.5-pro",
@@ -187,14 +297,20 @@ func Test_Model_TokenCount(t *testing.T) {
 			model:               "gemma-3-4b",
 			text:                "This tool uses Anthropic's newly released token counting api to count the number of tokens in a given text. Beware of existing tokenizers which are not accurate. Explore the source code here.",
 			expectedRemoteCount: 38,
+			// Gemma 3 extends the Gemma vocab and merges "'s" into one token
+			expectedLocalCount: 39,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			expected := tt.expectedRemoteCount
+			if tt.expectedLocalCount != 0 {
+				expected = tt.expectedLocalCount
+			}
 			count, err := tokenizer.Count(tokenizer.Model(tt.model), tt.text)
 			assert.NoError(t, err, "failed to count tokens for model %s", tt.model)
-			assert.Equal(t, tt.expectedRemoteCount, count, "Token count mismatch for model %s", tt.model)
+			assert.Equal(t, expected, count, "Token count mismatch for model %s", tt.model)
 		})
 	}
 }
@@ -267,6 +383,589 @@ func TestP50kBase(t *testing.T) {
 	runTests(t, tok, tests)
 }
 
+func TestP50kEdit(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.P50kEdit)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	// same vocabulary and pattern as p50k_base, runs of spaces have their
+	// own tokens from 50257 on
+	tests := []testCase{
+		{text: "hello world", ids: []uint{31373, 995}},
+		{text: "hello  world", ids: []uint{31373, 220, 995}},
+		{text: "hello   world", ids: []uint{31373, 50257, 995}},
+		{text: "supercalifragilistic", ids: []uint{16668, 9948, 361, 22562, 346, 2569}},
+		{text: "We know what we are, but know not what we may be.", ids: []uint{1135, 760, 644, 356, 389, 11, 475, 760, 407, 644, 356, 743, 307, 13}},
+		{text: "def f():\n    return  1", ids: []uint{4299, 277, 33529, 198, 50258, 1441, 220, 352}},
+		{text: "a  b   c    d", ids: []uint{64, 220, 275, 50257, 269, 50258, 288}},
+	}
+
+	runTests(t, tok, tests)
+}
+
+func TestGemmaBase(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.GemmaBase)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	// spaces are encoded as the ▁ marker, runs of them are single tokens
+	tests := []testCase{
+		{text: "hello world", ids: []uint{17534, 2134}},
+		{text: "Hello world", ids: []uint{4521, 2134}},
+		{text: "hello  world", ids: []uint{17534, 139, 9097}},
+		{text: "hello   world", ids: []uint{17534, 140, 9097}},
+		{text: " hello", ids: []uint{25612}},
+		{text: "supercalifragilistic", ids: []uint{3388, 1696, 648, 2086, 142091}},
+		{text: "We know what we are, but know not what we may be.", ids: []uint{1734, 1230, 1212, 783, 708, 235269, 901, 1230, 780, 1212, 783, 1249, 614, 235265}},
+		{text: "请考试我的软件！12345", ids: []uint{236343, 70132, 15409, 30149, 235482, 235274, 235284, 235304, 235310, 235308}},
+	}
+
+	runTests(t, tok, tests)
+}
+
+func TestSentencePieceBase(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.SentencePieceBase)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	// Llama 2 adds a dummy prefix, so a leading word starts with ▁
+	tests := []testCase{
+		{text: "Hello world", ids: []uint{15043, 3186}},
+		{text: " leading", ids: []uint{259, 25369}},
+		{text: "The turtle is faster than the hare.", ids: []uint{450, 260, 4227, 280, 338, 8473, 1135, 278, 447, 276, 29889}},
+		{text: "👍", ids: []uint{29871, 243, 162, 148, 144}},
+	}
+
+	runTests(t, tok, tests)
+
+	for _, model := range []tokenizer.Model{"llama2", "llama2:13b", "codellama:7b-instruct", "vicuna", "tinyllama"} {
+		encoding, err := tokenizer.EncodingForModel(model)
+		assert.NoError(t, err, model)
+		assert.Equal(t, tokenizer.SentencePieceBase, encoding, model)
+	}
+}
+
+func TestMistralBase(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.MistralBase)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	tests := []testCase{
+		{text: "The fox jumped over the hare.\nThe turtle is faster than the hare.", ids: []uint{415, 285, 1142, 14949, 754, 272, 295, 492, 28723, 13, 1014, 261, 3525, 291, 349, 9556, 821, 272, 295, 492, 28723}},
+	}
+
+	runTests(t, tok, tests)
+
+	for model, want := range map[tokenizer.Model]tokenizer.Encoding{
+		"mistral:7b":       tokenizer.MistralBase,
+		"mixtral:8x7b":     tokenizer.MistralBase,
+		"zephyr":           tokenizer.MistralBase,
+		"dolphin-mistral":  tokenizer.MistralBase,
+		"mistral-nemo:12b": tokenizer.R50kBase,
+		"mistral-small3.1": tokenizer.R50kBase,
+		"devstral:24b":     tokenizer.R50kBase,
+		"magistral:latest": tokenizer.R50kBase,
+	} {
+		encoding, err := tokenizer.EncodingForModel(model)
+		assert.NoError(t, err, model)
+		assert.Equal(t, want, encoding, model)
+	}
+}
+
+// TestWhitespace pins down how each encoding splits runs of spaces and tabs,
+// the ids were checked against reference BPE runs of the upstream patterns.
+func TestWhitespace(t *testing.T) {
+	texts := []string{
+		"hello  world",
+		"hello   world",
+		"hello    world",
+		"hello     world",
+		"hello      world",
+		"hello       world",
+		"hello        world",
+		"hello\t\tworld",
+		"hello\t\t\t\tworld",
+		"hello\t\t\t\t\t\t\t\tworld",
+		"if x:\n    return",
+		"if x:\n\t\treturn",
+		"a \tb",
+		"a\t b",
+		"a \t b",
+		"a\t  \tb",
+		"a  \t\t  b",
+		"end\n    ",
+	}
+
+	ids := map[tokenizer.Encoding][][]uint{
+		tokenizer.R50kBase: {
+			{31373, 220, 995},
+			{31373, 220, 220, 995},
+			{31373, 220, 220, 220, 995},
+			{31373, 220, 220, 220, 220, 995},
+			{31373, 220, 220, 220, 220, 220, 995},
+			{31373, 220, 220, 220, 220, 220, 220, 995},
+			{31373, 220, 220, 220, 220, 220, 220, 220, 995},
+			{31373, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 197, 197, 197, 197, 6894},
+			{361, 2124, 25, 198, 220, 220, 220, 1441},
+			{361, 2124, 25, 198, 197, 197, 7783},
+			{64, 220, 197, 65},
+			{64, 197, 275},
+			{64, 220, 197, 275},
+			{64, 197, 220, 220, 197, 65},
+			{64, 220, 220, 197, 197, 220, 275},
+			{437, 198, 220, 220, 220, 220},
+		},
+		tokenizer.P50kBase: {
+			{31373, 220, 995},
+			{31373, 50257, 995},
+			{31373, 50258, 995},
+			{31373, 50259, 995},
+			{31373, 50260, 995},
+			{31373, 50261, 995},
+			{31373, 50262, 995},
+			{31373, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 197, 197, 197, 197, 6894},
+			{361, 2124, 25, 198, 50258, 1441},
+			{361, 2124, 25, 198, 197, 197, 7783},
+			{64, 220, 197, 65},
+			{64, 197, 275},
+			{64, 220, 197, 275},
+			{64, 197, 50257, 197, 65},
+			{64, 50257, 197, 197, 220, 275},
+			{437, 198, 50259},
+		},
+		tokenizer.P50kEdit: {
+			{31373, 220, 995},
+			{31373, 50257, 995},
+			{31373, 50258, 995},
+			{31373, 50259, 995},
+			{31373, 50260, 995},
+			{31373, 50261, 995},
+			{31373, 50262, 995},
+			{31373, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 6894},
+			{31373, 197, 197, 197, 197, 197, 197, 197, 197, 6894},
+			{361, 2124, 25, 198, 50258, 1441},
+			{361, 2124, 25, 198, 197, 197, 7783},
+			{64, 220, 197, 65},
+			{64, 197, 275},
+			{64, 220, 197, 275},
+			{64, 197, 50257, 197, 65},
+			{64, 50257, 197, 197, 220, 275},
+			{437, 198, 50259},
+		},
+		tokenizer.Cl100kBase: {
+			{15339, 220, 1917},
+			{15339, 256, 1917},
+			{15339, 262, 1917},
+			{15339, 257, 1917},
+			{15339, 415, 1917},
+			{15339, 996, 1917},
+			{15339, 286, 1917},
+			{15339, 197, 77608},
+			{15339, 573, 77608},
+			{15339, 2750, 77608},
+			{333, 865, 512, 262, 471},
+			{333, 865, 512, 197, 862},
+			{64, 220, 2282},
+			{64, 197, 293},
+			{64, 7163, 293},
+			{64, 8860, 2282},
+			{64, 256, 8004, 293},
+			{408, 198, 257},
+		},
+		tokenizer.O200kBase: {
+			{24912, 220, 2375},
+			{24912, 256, 2375},
+			{24912, 271, 2375},
+			{24912, 257, 2375},
+			{24912, 530, 2375},
+			{24912, 1699, 2375},
+			{24912, 309, 2375},
+			{24912, 197, 156300},
+			{24912, 833, 156300},
+			{24912, 5216, 156300},
+			{366, 1215, 734, 271, 622},
+			{366, 1215, 734, 197, 1393},
+			{64, 220, 3722},
+			{64, 197, 287},
+			{64, 14593, 287},
+			{64, 18242, 3722},
+			{64, 256, 16609, 287},
+			{419, 198, 257},
+		},
+		tokenizer.OllamaLlamaBase: {
+			{15339, 220, 1917},
+			{15339, 256, 1917},
+			{15339, 262, 1917},
+			{15339, 257, 1917},
+			{15339, 415, 1917},
+			{15339, 996, 1917},
+			{15339, 286, 1917},
+			{15339, 197, 77608},
+			{15339, 573, 77608},
+			{15339, 2750, 77608},
+			{333, 865, 512, 262, 471},
+			{333, 865, 512, 197, 862},
+			{64, 220, 2282},
+			{64, 197, 293},
+			{64, 7163, 293},
+			{64, 8860, 2282},
+			{64, 256, 8004, 293},
+			{408, 198, 257},
+		},
+		tokenizer.AnthropicBase: {
+			{9381, 225, 2253},
+			{9381, 261, 2253},
+			{9381, 264, 2253},
+			{9381, 262, 2253},
+			{9381, 888, 2253},
+			{9381, 1013, 2253},
+			{9381, 277, 2253},
+			{9381, 202, 202, 6778},
+			{9381, 9766, 202, 6778},
+			{9381, 53454, 202, 6778},
+			{390, 679, 30, 295, 449},
+			{390, 679, 30, 600, 202, 1172},
+			{69, 225, 202, 70},
+			{69, 202, 301},
+			{69, 15369, 301},
+			{69, 64268, 202, 70},
+			{69, 261, 418, 225, 301},
+			{441, 555},
+		},
+		tokenizer.GemmaBase: {
+			{17534, 139, 9097},
+			{17534, 140, 9097},
+			{17534, 141, 9097},
+			{17534, 142, 9097},
+			{17534, 143, 9097},
+			{17534, 144, 9097},
+			{17534, 145, 9097},
+			{17534, 255969, 9097},
+			{17534, 255971, 9097},
+			{17534, 255975, 9097},
+			{648, 1141, 235292, 108, 141, 773},
+			{648, 1141, 235292, 108, 255969, 773},
+			{235250, 235248, 226, 235268},
+			{235250, 226, 518},
+			{235250, 235248, 226, 518},
+			{235250, 226, 139, 226, 235268},
+			{235250, 139, 255969, 139, 235268},
+			{615, 108, 141},
+		},
+	}
+
+	for encoding, want := range ids {
+		t.Run(string(encoding), func(t *testing.T) {
+			tok, err := tokenizer.Get(encoding)
+			if err != nil {
+				t.Fatalf("can't create tokenizer: %v", err)
+			}
+
+			tests := make([]testCase, len(texts))
+			for i, text := range texts {
+				tests[i] = testCase{text: text, ids: want[i]}
+			}
+			runTests(t, tok, tests)
+		})
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := []struct {
+		model    tokenizer.Model
+		encoding tokenizer.Encoding
+	}{
+		{model: tokenizer.GPT4o, encoding: tokenizer.O200kBase},
+		{model: tokenizer.TextDavinciEdit001, encoding: tokenizer.P50kEdit},
+		{model: "gpt-4o-2024-08-06", encoding: tokenizer.O200kBase},
+		{model: "claude-3-5-sonnet-20241022", encoding: tokenizer.AnthropicBase},
+		// the longest prefix wins over "llava"
+		{model: "llava-llama3:8b", encoding: tokenizer.OllamaLlamaBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			encoding, err := tokenizer.EncodingForModel(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+
+	_, err := tokenizer.EncodingForModel("not-a-model-20250101")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
+
+func TestGetName(t *testing.T) {
+	for _, enc := range []tokenizer.Encoding{
+		tokenizer.R50kBase, tokenizer.P50kBase, tokenizer.P50kEdit, tokenizer.Cl100kBase,
+		tokenizer.O200kBase, tokenizer.OllamaLlamaBase, tokenizer.AnthropicBase, tokenizer.GemmaBase,
+		tokenizer.SentencePieceBase, tokenizer.MistralBase,
+	} {
+		t.Run(string(enc), func(t *testing.T) {
+			tok, err := tokenizer.Get(enc)
+			assert.NoError(t, err)
+			assert.Equal(t, string(enc), tok.GetName())
+		})
+	}
+}
+
+func TestForModelGPT5(t *testing.T) {
+	for _, model := range []tokenizer.Model{tokenizer.GPT5, tokenizer.GPT5Mini, tokenizer.GPT5Nano} {
+		t.Run(string(model), func(t *testing.T) {
+			enc, err := tokenizer.ForModel(model)
+			assert.NoError(t, err)
+			assert.Equal(t, "o200k_base", enc.GetName())
+		})
+	}
+}
+
+func TestEncodingForModelProviders(t *testing.T) {
+	tests := []struct {
+		model    tokenizer.Model
+		encoding tokenizer.Encoding
+	}{
+		// OpenRouter
+		{model: "openai/gpt-4o", encoding: tokenizer.O200kBase},
+		{model: "openai/gpt-4-turbo", encoding: tokenizer.Cl100kBase},
+		{model: "anthropic/claude-3-5-sonnet", encoding: tokenizer.AnthropicBase},
+		{model: "google/gemma-2-9b-it", encoding: tokenizer.GemmaBase},
+		// Bedrock
+		{model: "bedrock/anthropic.claude-3-sonnet-v1", encoding: tokenizer.AnthropicBase},
+		{model: "anthropic.claude-3-5-sonnet-20241022-v2:0", encoding: tokenizer.AnthropicBase},
+		{model: "us.anthropic.claude-3-7-sonnet-20250219-v1:0", encoding: tokenizer.AnthropicBase},
+		// Azure
+		{model: "azure/gpt-35-turbo", encoding: tokenizer.Cl100kBase},
+		{model: "azure/gpt-4o-2024-08-06", encoding: tokenizer.O200kBase},
+		// a dot inside the model name isn't a provider
+		{model: "gpt-4.1", encoding: tokenizer.O200kBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			encoding, err := tokenizer.EncodingForModel(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+
+	// the ratio for Claude models applies as well
+	text := "We know what we are, but know not what we may be."
+	want, _ := tokenizer.Count("claude-3-5-sonnet", text)
+	count, err := tokenizer.Count("bedrock/anthropic.claude-3-5-sonnet", text)
+	assert.NoError(t, err)
+	assert.Equal(t, want, count)
+
+	_, err = tokenizer.ForModel("openai/not-a-model")
+	var modelErr *tokenizer.UnsupportedModelError
+	if assert.ErrorAs(t, err, &modelErr) {
+		assert.Equal(t, tokenizer.Model("openai/not-a-model"), modelErr.Model)
+	}
+}
+
+func TestEncodingForModelSnapshots(t *testing.T) {
+	tests := []struct {
+		model    tokenizer.Model
+		encoding tokenizer.Encoding
+	}{
+		{model: "gpt-4o-2024-08-06", encoding: tokenizer.O200kBase},
+		{model: "gpt-4o-mini-2024-07-18", encoding: tokenizer.O200kBase},
+		{model: "gpt-4.1-2025-04-14", encoding: tokenizer.O200kBase},
+		{model: "o1-2024-12-17", encoding: tokenizer.O200kBase},
+		{model: "o3-mini-2025-01-31", encoding: tokenizer.O200kBase},
+		{model: "gpt-4-0613", encoding: tokenizer.Cl100kBase},
+		{model: "gpt-3.5-turbo-0125", encoding: tokenizer.Cl100kBase},
+		{model: "text-embedding-ada-002-v2", encoding: tokenizer.Cl100kBase},
+		{model: "claude-opus-4-1-20250805", encoding: tokenizer.AnthropicBase},
+		{model: "claude-sonnet-4-20250514", encoding: tokenizer.AnthropicBase},
+		{model: "claude-3-5-sonnet-20241022", encoding: tokenizer.AnthropicBase},
+		{model: "claude-3-5-sonnet-latest", encoding: tokenizer.AnthropicBase},
+		{model: "claude-3-opus", encoding: tokenizer.AnthropicBase},
+		// version suffixes that are part of the family name are kept
+		{model: "deepseek-v3", encoding: tokenizer.R50kBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			encoding, err := tokenizer.EncodingForModel(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+}
+
+func TestForModelNormalizesName(t *testing.T) {
+	tests := []struct {
+		model tokenizer.Model
+		name  string
+	}{
+		{model: "GPT-4o", name: "o200k_base"},
+		{model: " claude-3-5-sonnet ", name: "anthropic"},
+		{model: "\tClaude-Opus-4-1-20250805\n", name: "anthropic"},
+		{model: "Gemma3", name: "gemma"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			tok, err := tokenizer.ForModel(tt.model)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.name, tok.GetName())
+		})
+	}
+
+	text := "We know what we are, but know not what we may be."
+	want, err := tokenizer.Count("claude-3-5-sonnet-20241022", text)
+	assert.NoError(t, err)
+	got, err := tokenizer.Count(" Claude-3-5-Sonnet-20241022 ", text)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestO200kSpecialTokens(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.O200kBase)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	allowed := map[string]bool{"<|endoftext|>": true, "<|endofprompt|>": true}
+	ids, tokens, err := tok.EncodeWithSpecialTokens("hello world<|endoftext|>", allowed)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{24912, 2375, 199999}, ids)
+	assert.Equal(t, "<|endoftext|>", tokens[2])
+
+	ids, _, err = tok.EncodeWithSpecialTokens("<|endofprompt|>", allowed)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{200018}, ids)
+
+	// plain Encode keeps treating it as text
+	ids, _, err = tok.Encode("<|endoftext|>")
+	assert.NoError(t, err)
+	assert.Greater(t, len(ids), 1)
+}
+
+func TestForModelOrDefault(t *testing.T) {
+	var fallbacks []tokenizer.Model
+	tokenizer.SetOnFallback(func(model tokenizer.Model, _ tokenizer.Encoding) {
+		fallbacks = append(fallbacks, model)
+	})
+	t.Cleanup(func() { tokenizer.SetOnFallback(nil) })
+
+	tok, err := tokenizer.ForModelOrDefault(tokenizer.GPT4o, tokenizer.Cl100kBase)
+	assert.NoError(t, err)
+	assert.Equal(t, "o200k_base", tok.GetName())
+	assert.Empty(t, fallbacks)
+
+	tok, err = tokenizer.ForModelOrDefault("brand-new-model", tokenizer.Cl100kBase)
+	assert.NoError(t, err)
+	assert.Equal(t, "cl100k_base", tok.GetName())
+	assert.Equal(t, []tokenizer.Model{"brand-new-model"}, fallbacks)
+
+	_, err = tokenizer.ForModelOrDefault("brand-new-model", "not-an-encoding")
+	assert.ErrorIs(t, err, tokenizer.ErrEncodingNotSupported)
+}
+
+// TestSetOnFallbackConcurrent swaps the fallback hook while other goroutines
+// fall back, for go test -race.
+func TestSetOnFallbackConcurrent(t *testing.T) {
+	t.Cleanup(func() { tokenizer.SetOnFallback(nil) })
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tokenizer.SetOnFallback(func(tokenizer.Model, tokenizer.Encoding) {})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := tokenizer.ForModelOrDefault("brand-new-model", tokenizer.Cl100kBase)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUnsupportedErrors(t *testing.T) {
+	_, err := tokenizer.Count("brand-new-model", "hello")
+	var modelErr *tokenizer.UnsupportedModelError
+	if assert.ErrorAs(t, err, &modelErr) {
+		assert.Equal(t, tokenizer.Model("brand-new-model"), modelErr.Model)
+	}
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+
+	_, err = tokenizer.Get("not-an-encoding")
+	var encodingErr *tokenizer.UnsupportedEncodingError
+	if assert.ErrorAs(t, err, &encodingErr) {
+		assert.Equal(t, tokenizer.Encoding("not-an-encoding"), encodingErr.Encoding)
+	}
+	assert.ErrorIs(t, err, tokenizer.ErrEncodingNotSupported)
+	assert.EqualError(t, err, `encoding not supported: "not-an-encoding"`)
+}
+
+func TestSetDefaultEncoding(t *testing.T) {
+	_, err := tokenizer.ForModel("brand-new-model")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+
+	tokenizer.SetDefaultEncoding(tokenizer.Cl100kBase)
+	t.Cleanup(func() { tokenizer.SetDefaultEncoding("") })
+
+	tok, err := tokenizer.ForModel("brand-new-model")
+	assert.NoError(t, err)
+	assert.Equal(t, "cl100k_base", tok.GetName())
+
+	count, err := tokenizer.Count("brand-new-model", "hello world")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestP50kEditFIM(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.P50kEdit)
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+
+	prompt := "<|fim_prefix|>def add(a, b):\n<|fim_suffix|>\n    return c<|fim_middle|>"
+	allowed := map[string]bool{
+		"<|fim_prefix|>": true,
+		"<|fim_middle|>": true,
+		"<|fim_suffix|>": true,
+	}
+
+	ids, tokens, err := tok.EncodeWithSpecialTokens(prompt, allowed)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+
+	markers := map[uint]string{}
+	for i, id := range ids {
+		if id >= 50281 && id <= 50283 {
+			markers[id] = tokens[i]
+		}
+	}
+	assert.Equal(t, map[uint]string{50281: "<|fim_prefix|>", 50282: "<|fim_middle|>", 50283: "<|fim_suffix|>"}, markers)
+	assert.Equal(t, uint(50281), ids[0])
+	assert.Equal(t, uint(50282), ids[len(ids)-1])
+
+	text, err := tok.Decode(ids)
+	assert.NoError(t, err)
+	assert.Equal(t, prompt, text)
+
+	// markers that are not allowed are plain text
+	ids, _, err = tok.EncodeWithSpecialTokens(prompt, nil)
+	assert.NoError(t, err)
+	plain, _, err := tok.Encode(prompt)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, ids)
+}
+
 func runTests(t *testing.T, tok tokenizer.Codec, tests []testCase) {
 	for _, test := range tests {
 		t.Run(test.text, func(t *testing.T) {
@@ -336,3 +1035,381 @@ func readEnvFile(path string) (map[string]string, error) {
 
 	return env, nil
 }
+
+func TestCountDetailed(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+
+	raw, adjusted, ratio, err := tokenizer.CountDetailed("claude-3-5-sonnet-20241022", text)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.166, ratio)
+	assert.Equal(t, int(float64(raw)*1.166), adjusted)
+	assert.NotEqual(t, raw, adjusted)
+
+	count, err := tokenizer.Count("claude-3-5-sonnet-20241022", text)
+	assert.NoError(t, err)
+	assert.Equal(t, adjusted, count)
+
+	raw, adjusted, ratio, err = tokenizer.CountDetailed(tokenizer.GPT4o, text)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, ratio)
+	assert.Equal(t, raw, adjusted)
+
+	_, _, _, err = tokenizer.CountDetailed("brand-new-model", text)
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
+
+func TestEmptyAndWhitespaceInput(t *testing.T) {
+	encodings := []tokenizer.Encoding{
+		tokenizer.R50kBase,
+		tokenizer.P50kBase,
+		tokenizer.P50kEdit,
+		tokenizer.Cl100kBase,
+		tokenizer.O200kBase,
+		tokenizer.OllamaLlamaBase,
+		tokenizer.AnthropicBase,
+		tokenizer.GemmaBase,
+		tokenizer.SentencePieceBase,
+		tokenizer.MistralBase,
+	}
+	inputs := []string{"", " ", "  ", "\n", "\n\n", "\t", " \t\n ", "\r\n"}
+
+	for _, encoding := range encodings {
+		tok, err := tokenizer.Get(encoding)
+		if err != nil {
+			t.Fatalf("can't create tokenizer %s: %v", encoding, err)
+		}
+
+		for _, input := range inputs {
+			t.Run(fmt.Sprintf("%s/%q", encoding, input), func(t *testing.T) {
+				ids, tokens, err := tok.Encode(input)
+				assert.NoError(t, err)
+				assert.NotNil(t, ids)
+				assert.NotNil(t, tokens)
+				assert.Len(t, tokens, len(ids))
+
+				ordinary, err := tok.EncodeOrdinary(input)
+				assert.NoError(t, err)
+				assert.NotNil(t, ordinary)
+				assert.Equal(t, ids, ordinary)
+
+				special, _, err := tok.EncodeWithSpecialTokens(input, nil)
+				assert.NoError(t, err)
+				assert.NotNil(t, special)
+				assert.Equal(t, ids, special)
+
+				count, err := tok.Count(input)
+				assert.NoError(t, err)
+				assert.Equal(t, len(ids), count)
+
+				text, err := tok.Decode(ids)
+				assert.NoError(t, err)
+				assert.Equal(t, input, text)
+
+				if input == "" {
+					assert.Empty(t, ids)
+					assert.Equal(t, 0, count)
+				} else {
+					assert.NotEmpty(t, ids)
+				}
+			})
+		}
+
+		t.Run(fmt.Sprintf("%s/nil", encoding), func(t *testing.T) {
+			text, err := tok.Decode(nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "", text)
+
+			raw, err := tok.DecodeToBytes(nil)
+			assert.NoError(t, err)
+			assert.Empty(t, raw)
+
+			var buf bytes.Buffer
+			n, err := tok.DecodeTo(&buf, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, n)
+		})
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	prefix, encoding, ok := tokenizer.ResolveModel("llama3.2-vision")
+	assert.True(t, ok)
+	assert.Equal(t, "llama3.2-vision", prefix)
+	assert.Equal(t, tokenizer.OllamaLlamaBase, encoding)
+
+	prefix, encoding, ok = tokenizer.ResolveModel("openrouter/GPT-4o-2024-08-06")
+	assert.True(t, ok)
+	assert.Equal(t, "gpt-4o-", prefix)
+	assert.Equal(t, tokenizer.O200kBase, encoding)
+
+	_, _, ok = tokenizer.ResolveModel("brand-new-model")
+	assert.False(t, ok)
+}
+
+func TestNormalizeModel(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		model    tokenizer.Model
+		encoding tokenizer.Encoding
+	}{
+		{"GPT-4o-2024-08-06", tokenizer.GPT4o, tokenizer.O200kBase},
+		{"  openai/gpt-4o-mini-2024-07-18 ", tokenizer.GPT4oMini, tokenizer.O200kBase},
+		{"openrouter/openai/gpt-4-0613", tokenizer.GPT4, tokenizer.Cl100kBase},
+		{"gpt-4o", tokenizer.GPT4o, tokenizer.O200kBase},
+		{"claude-3-5-sonnet-latest", "claude-3-5-sonnet", tokenizer.AnthropicBase},
+		{"claude-3-5-sonnet@20240620", "claude-3-5-sonnet", tokenizer.AnthropicBase},
+		{"bedrock/anthropic.claude-3-sonnet-20240229-v1:0", "claude-3-sonnet", tokenizer.AnthropicBase},
+		{"llama3.1:8b", "llama3.1", tokenizer.OllamaLlamaBase},
+		{"ollama/qwen2.5:14b-instruct", "qwen2.5", tokenizer.R50kBase},
+		{"deepseek-v3", "deepseek-v3", tokenizer.R50kBase},
+	} {
+		model, encoding, err := tokenizer.NormalizeModel(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.model, model, tt.input)
+		assert.Equal(t, tt.encoding, encoding, tt.input)
+	}
+
+	_, _, err := tokenizer.NormalizeModel("brand-new-model")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
+
+func TestModelTags(t *testing.T) {
+	for model, want := range map[tokenizer.Model]tokenizer.Encoding{
+		"llama3.1:8b":                 tokenizer.OllamaLlamaBase,
+		"llama3.1:70b-instruct-q4_0":  tokenizer.OllamaLlamaBase,
+		"qwen2.5:14b":                 tokenizer.R50kBase,
+		"qwen2.5:14b-instruct":        tokenizer.R50kBase,
+		"ollama/gemma2:9b":            tokenizer.GemmaBase,
+		"claude-3-5-sonnet@20240620":  tokenizer.AnthropicBase,
+		"gpt-4o@2024-08-06":           tokenizer.O200kBase,
+		"ft:gpt-3.5-turbo:org::abc12": tokenizer.Cl100kBase,
+	} {
+		encoding, err := tokenizer.EncodingForModel(model)
+		assert.NoError(t, err, model)
+		assert.Equal(t, want, encoding, model)
+	}
+
+	prefix, _, ok := tokenizer.ResolveModel("llama3.1:8b")
+	assert.True(t, ok)
+	assert.Equal(t, "llama3.1", prefix)
+}
+
+func TestPhiModels(t *testing.T) {
+	for model, want := range map[tokenizer.Model]tokenizer.Encoding{
+		"phi4":                tokenizer.Cl100kBase,
+		"phi4:14b":            tokenizer.Cl100kBase,
+		"phi4-reasoning":      tokenizer.Cl100kBase,
+		"phi4-mini":           tokenizer.O200kBase,
+		"phi4-mini:3.8b":      tokenizer.O200kBase,
+		"phi4-mini-reasoning": tokenizer.O200kBase,
+	} {
+		encoding, err := tokenizer.EncodingForModel(model)
+		assert.NoError(t, err, model)
+		assert.Equal(t, want, encoding, model)
+	}
+
+	// Phi-4 extends the tiktoken vocabularies, so ordinary text encodes
+	// exactly like them and unlike r50k_base
+	text := "def total(prices):\n        return sum(p * 1.25 for p in prices)\n"
+	for model, encoding := range map[tokenizer.Model]tokenizer.Encoding{
+		"phi4":      tokenizer.Cl100kBase,
+		"phi4-mini": tokenizer.O200kBase,
+	} {
+		tok, err := tokenizer.ForModel(model)
+		assert.NoError(t, err)
+		ref, err := tokenizer.Get(encoding)
+		assert.NoError(t, err)
+		ids, _, err := tok.Encode(text)
+		assert.NoError(t, err)
+		want, _, err := ref.Encode(text)
+		assert.NoError(t, err)
+		assert.Equal(t, want, ids, model)
+	}
+
+	r50kTok, err := tokenizer.Get(tokenizer.R50kBase)
+	assert.NoError(t, err)
+	r50k, err := r50kTok.Count(text)
+	assert.NoError(t, err)
+	phi4, err := tokenizer.Count("phi4", text)
+	assert.NoError(t, err)
+	assert.Less(t, phi4, r50k)
+}
+
+func TestBertBase(t *testing.T) {
+	tok, err := tokenizer.ForModel("all-minilm:l6-v2")
+	if err != nil {
+		t.Fatalf("can't create tokenizer: %v", err)
+	}
+	assert.Equal(t, "bert", tok.GetName())
+
+	ids, tokens, err := tok.Encode("Embeddings are simple.")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"em", "##bed", "##ding", "##s", "are", "simple", "."}, tokens)
+	assert.Equal(t, []uint{7861, 8270, 4667, 2015, 2024, 3722, 1012}, ids)
+}
+
+// TestGetIsolation adds a special token to one codec while another goroutine
+// encodes with a second codec of the same encoding, for go test -race to
+// check they share no mutable state.
+func TestGetIsolation(t *testing.T) {
+	for _, encoding := range []tokenizer.Encoding{tokenizer.OllamaLlamaBase, tokenizer.O200kBase} {
+		first, err := tokenizer.Get(encoding)
+		assert.NoError(t, err)
+		second, err := tokenizer.Get(encoding)
+		assert.NoError(t, err)
+		special := first.(*codec.Codec).SpecialTokensSorted()
+		id := special[len(special)-1].ID + 1
+		allowed := map[string]bool{"<|tool|>": true}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, first.(*codec.Codec).AddSpecialToken("<|tool|>", id))
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_, tokens, err := second.EncodeWithSpecialTokens("<|tool|>hello", allowed)
+				assert.NoError(t, err)
+				assert.NotContains(t, tokens, "<|tool|>")
+				_, err = second.Decode([]uint{id})
+				assert.Error(t, err)
+			}
+		}()
+		wg.Wait()
+
+		assert.Contains(t, first.SpecialTokens(), "<|tool|>")
+		assert.NotContains(t, second.SpecialTokens(), "<|tool|>")
+		ids, _, err := first.EncodeWithSpecialTokens("<|tool|>hello", allowed)
+		assert.NoError(t, err)
+		assert.Equal(t, id, ids[0])
+	}
+}
+
+// TestConcurrentGet creates and uses every encoding from many goroutines at
+// once, for go test -race to check the lazy vocabulary init.
+func TestConcurrentGet(t *testing.T) {
+	encodings := []tokenizer.Encoding{
+		tokenizer.R50kBase,
+		tokenizer.P50kBase,
+		tokenizer.P50kEdit,
+		tokenizer.Cl100kBase,
+		tokenizer.O200kBase,
+		tokenizer.OllamaLlamaBase,
+		tokenizer.AnthropicBase,
+		tokenizer.GemmaBase,
+		tokenizer.BertBase,
+		tokenizer.SentencePieceBase,
+		tokenizer.MistralBase,
+	}
+	shared := make(map[tokenizer.Encoding]tokenizer.Codec)
+	for _, encoding := range encodings {
+		tok, err := tokenizer.Get(encoding)
+		if err != nil {
+			t.Fatalf("can't create tokenizer %s: %v", encoding, err)
+		}
+		shared[encoding] = tok
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		for _, encoding := range encodings {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, tok := range []tokenizer.Codec{nil, shared[encoding]} {
+					if tok == nil {
+						var err error
+						if tok, err = tokenizer.Get(encoding); err != nil {
+							t.Errorf("can't create tokenizer %s: %v", encoding, err)
+							return
+						}
+					}
+					ids, _, err := tok.Encode("hello world")
+					if err != nil {
+						t.Errorf("%s: error encoding: %v", encoding, err)
+						return
+					}
+					if _, err := tok.Decode(ids); err != nil {
+						t.Errorf("%s: error decoding: %v", encoding, err)
+					}
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestForModelGPT4oMini(t *testing.T) {
+	tok, err := tokenizer.ForModel(tokenizer.GPT4oMini)
+	assert.NoError(t, err)
+	assert.Equal(t, "o200k_base", tok.GetName())
+
+	// matched by the explicit case, not the gpt-4o- prefix
+	prefix, encoding, ok := tokenizer.ResolveModel(tokenizer.GPT4oMini)
+	assert.True(t, ok)
+	assert.Equal(t, "gpt-4o-mini", prefix)
+	assert.Equal(t, tokenizer.O200kBase, encoding)
+}
+
+func TestForModelWithRatio(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+	model := tokenizer.Model("anthropic/claude-3-5-sonnet-20241022")
+
+	enc, err := tokenizer.ForModelWithRatio(model)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.166, enc.(*tokenizer.RatioCodec).Ratio)
+
+	count, err := enc.Count(text)
+	assert.NoError(t, err)
+	want, err := tokenizer.Count(model, text)
+	assert.NoError(t, err)
+	assert.Equal(t, want, count)
+
+	ids, _, err := enc.Encode(text)
+	assert.NoError(t, err)
+	assert.Less(t, len(ids), count)
+	decoded, err := enc.Decode(ids)
+	assert.NoError(t, err)
+	assert.Equal(t, text, decoded)
+
+	_, err = tokenizer.ForModelWithRatio("brand-new-model")
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}
+
+func TestWarmup(t *testing.T) {
+	assert.NoError(t, tokenizer.Warmup(tokenizer.O200kBase, tokenizer.OllamaLlamaBase, tokenizer.GemmaBase))
+
+	// loading a vocabulary allocates for each of its entries
+	for _, encoding := range []tokenizer.Encoding{tokenizer.O200kBase, tokenizer.OllamaLlamaBase, tokenizer.GemmaBase} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := tokenizer.Get(encoding)
+		runtime.ReadMemStats(&after)
+		assert.NoError(t, err)
+		assert.Less(t, after.Mallocs-before.Mallocs, uint64(1000), encoding)
+	}
+
+	err := tokenizer.Warmup(tokenizer.Cl100kBase, "unknown")
+	assert.ErrorIs(t, err, tokenizer.ErrEncodingNotSupported)
+}
+
+func TestEmptyModel(t *testing.T) {
+	for _, model := range []tokenizer.Model{"", " ", "\t\n "} {
+		_, err := tokenizer.Count(model, "hello")
+		assert.ErrorIs(t, err, tokenizer.ErrEmptyModel)
+		assert.NotErrorIs(t, err, tokenizer.ErrModelNotSupported)
+
+		_, err = tokenizer.EncodingForModel(model)
+		assert.ErrorIs(t, err, tokenizer.ErrEmptyModel)
+		_, _, err = tokenizer.NormalizeModel(string(model))
+		assert.ErrorIs(t, err, tokenizer.ErrEmptyModel)
+	}
+
+	// the default encoding is for unknown models, not missing ones
+	tokenizer.SetDefaultEncoding(tokenizer.Cl100kBase)
+	t.Cleanup(func() { tokenizer.SetDefaultEncoding("") })
+	_, err := tokenizer.ForModel("")
+	assert.ErrorIs(t, err, tokenizer.ErrEmptyModel)
+}
@@ -0,0 +1,180 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// toolsOverhead is the number of tokens the API adds around the tool
+// definitions in a prompt.
+const toolsOverhead = 9
+
+// ToolDef is a function tool as sent in the tools field of a chat request.
+// Parameters holds its JSON schema.
+type ToolDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// CountTools estimates the prompt tokens the tool definitions add to a chat
+// request. OpenAI renders tools into the prompt as a TypeScript namespace,
+// which is rebuilt here and counted with the encoding of the model, plus a
+// fixed overhead. The result is an estimate: the rendering isn't documented
+// and schema keywords other than type, description, enum, items, properties
+// and required are ignored.
+func CountTools(model Model, tools []ToolDef) (int, error) {
+	if len(tools) == 0 {
+		return 0, nil
+	}
+	text, err := formatTools(tools)
+	if err != nil {
+		return 0, err
+	}
+	count, err := Count(model, text)
+	if err != nil {
+		return 0, err
+	}
+	return count + toolsOverhead, nil
+}
+
+type toolSchema struct {
+	Type        string         `json:"type"`
+	Description string         `json:"description"`
+	Enum        []any          `json:"enum"`
+	Items       *toolSchema    `json:"items"`
+	Properties  toolProperties `json:"properties"`
+	Required    []string       `json:"required"`
+}
+
+type toolProperty struct {
+	name   string
+	schema toolSchema
+}
+
+// toolProperties keeps the properties of a schema in the order they were
+// written, as the rendering depends on it.
+type toolProperties []toolProperty
+
+func (p *toolProperties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var schema toolSchema
+		if err := dec.Decode(&schema); err != nil {
+			return err
+		}
+		*p = append(*p, toolProperty{name: key.(string), schema: schema})
+	}
+	return nil
+}
+
+func formatTools(tools []ToolDef) (string, error) {
+	lines := []string{"namespace functions {", ""}
+	for _, tool := range tools {
+		var params toolSchema
+		if len(tool.Parameters) > 0 {
+			if err := json.Unmarshal(tool.Parameters, &params); err != nil {
+				return "", fmt.Errorf("tool %s: invalid parameters: %v", tool.Name, err)
+			}
+		}
+
+		if tool.Description != "" {
+			lines = append(lines, "// "+tool.Description)
+		}
+		if len(params.Properties) > 0 {
+			props, err := formatToolProperties(params, 0)
+			if err != nil {
+				return "", fmt.Errorf("tool %s: %v", tool.Name, err)
+			}
+			lines = append(lines, "type "+tool.Name+" = (_: {", props, "}) => any;")
+		} else {
+			lines = append(lines, "type "+tool.Name+" = () => any;")
+		}
+		lines = append(lines, "")
+	}
+	lines = append(lines, "} // namespace functions")
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatToolProperties(schema toolSchema, indent int) (string, error) {
+	var lines []string
+	for _, prop := range schema.Properties {
+		if prop.schema.Description != "" && indent < 2 {
+			lines = append(lines, "// "+prop.schema.Description)
+		}
+		typ, err := formatToolType(prop.schema, indent)
+		if err != nil {
+			return "", err
+		}
+		name := prop.name + "?"
+		for _, required := range schema.Required {
+			if required == prop.name {
+				name = prop.name
+				break
+			}
+		}
+		lines = append(lines, name+": "+typ+",")
+	}
+
+	pad := strings.Repeat(" ", indent)
+	for i := range lines {
+		lines[i] = pad + lines[i]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatToolType(schema toolSchema, indent int) (string, error) {
+	switch schema.Type {
+	case "string", "number", "integer":
+		if len(schema.Enum) > 0 {
+			values := make([]string, len(schema.Enum))
+			for i, v := range schema.Enum {
+				if schema.Type == "string" {
+					values[i] = `"` + fmt.Sprint(v) + `"`
+				} else {
+					values[i] = fmt.Sprint(v)
+				}
+			}
+			return strings.Join(values, " | "), nil
+		}
+		if schema.Type == "string" {
+			return "string", nil
+		}
+		return "number", nil
+	case "array":
+		if schema.Items == nil {
+			return "any[]", nil
+		}
+		typ, err := formatToolType(*schema.Items, indent)
+		if err != nil {
+			return "", err
+		}
+		return typ + "[]", nil
+	case "boolean", "null":
+		return schema.Type, nil
+	case "object":
+		props, err := formatToolProperties(schema, indent+2)
+		if err != nil {
+			return "", err
+		}
+		return "{\n" + props + "\n}", nil
+	default:
+		return "", fmt.Errorf("unsupported parameter type: %q", schema.Type)
+	}
+}
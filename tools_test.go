@@ -0,0 +1,57 @@
+package tokenizer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/awee-ai/go-tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountTools(t *testing.T) {
+	tools := []tokenizer.ToolDef{{
+		Name:        "get_current_weather",
+		Description: "Get the current weather in a given location",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"location": {"type": "string", "description": "The city and state, e.g. San Francisco, CA"},
+				"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}
+			},
+			"required": ["location"]
+		}`),
+	}}
+
+	rendered := `namespace functions {
+
+// Get the current weather in a given location
+type get_current_weather = (_: {
+// The city and state, e.g. San Francisco, CA
+location: string,
+unit?: "celsius" | "fahrenheit",
+}) => any;
+
+} // namespace functions`
+	want, err := tokenizer.Count(tokenizer.GPT4o, rendered)
+	assert.NoError(t, err)
+
+	count, err := tokenizer.CountTools(tokenizer.GPT4o, tools)
+	assert.NoError(t, err)
+	assert.Equal(t, want+9, count)
+	assert.InDelta(t, 60, count, 5)
+
+	count, err = tokenizer.CountTools(tokenizer.GPT4o, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestCountToolsErrors(t *testing.T) {
+	_, err := tokenizer.CountTools(tokenizer.GPT4o, []tokenizer.ToolDef{{
+		Name:       "f",
+		Parameters: json.RawMessage(`{"type": "object", "properties": {"x": {"type": "tuple"}}}`),
+	}})
+	assert.ErrorContains(t, err, "unsupported parameter type")
+
+	_, err = tokenizer.CountTools("unknown-model", []tokenizer.ToolDef{{Name: "f"}})
+	assert.ErrorIs(t, err, tokenizer.ErrModelNotSupported)
+}